@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+)
+
+// chargeLimitCmd implements "charge-limit get" and "charge-limit set <start> <end>"
+func chargeLimitCmd() {
+	args := parseFlags("charge-limit")
+	cfg, logPath := loadPaths()
+	src := sysfs.NewSource(cfg.SysfsRoot)
+
+	if len(args) == 0 || args[0] == "get" {
+		printChargeControls(src)
+		return
+	}
+	if args[0] != "set" || len(args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: battery-zen charge-limit get|set <start> <end>")
+		os.Exit(2)
+	}
+
+	start, err1 := strconv.Atoi(args[1])
+	end, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		log.Fatalf("charge-limit: thresholds must be integers")
+	}
+	if err := src.SetChargeThresholds(start, end); err != nil {
+		log.Fatalf("charge-limit: %v", err)
+	}
+	logChargeEvent(cfg, logPath, &profileDaemon{}, fmt.Sprintf("charge_limit=%d-%d", start, end))
+	printChargeControls(src)
+}
+
+// chargeBehaviourCmd implements "charge-behaviour [auto|inhibit-charge|force-discharge]"
+func chargeBehaviourCmd() {
+	args := parseFlags("charge-behaviour")
+	cfg, logPath := loadPaths()
+	src := sysfs.NewSource(cfg.SysfsRoot)
+
+	if len(args) == 0 {
+		printChargeControls(src)
+		return
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: battery-zen charge-behaviour [auto|inhibit-charge|force-discharge]")
+		os.Exit(2)
+	}
+	if err := src.SetChargeBehaviour(args[0]); err != nil {
+		log.Fatalf("charge-behaviour: %v", err)
+	}
+	logChargeEvent(cfg, logPath, &profileDaemon{}, "charge_behaviour="+args[0])
+	printChargeControls(src)
+}
+
+func printChargeControls(src *sysfs.Source) {
+	controls := src.ChargeControls()
+	if len(controls) == 0 {
+		fmt.Println("no battery found")
+		return
+	}
+	for _, c := range controls {
+		fmt.Printf("%s:", c.Pack)
+		if c.HasThresholds() {
+			if c.Start >= 0 {
+				fmt.Printf(" start=%d", c.Start)
+			}
+			fmt.Printf(" end=%d", c.End)
+		} else {
+			fmt.Print(" thresholds=unsupported")
+		}
+		if len(c.Behaviours) > 0 {
+			fmt.Printf(" behaviour=%s (available: %s)", c.Behaviour, strings.Join(c.Behaviours, ", "))
+		} else {
+			fmt.Print(" behaviour=unsupported")
+		}
+		fmt.Println()
+	}
+}
+
+// logChargeEvent records a settings change in the CSV log alongside a sample
+func logChargeEvent(cfg config.Config, logPath string, pd *profileDaemon, event string) {
+	if err := logSample(cfg, logPath, pd, event); err != nil {
+		log.Printf("log event %q: %v", event, err)
+	}
+}
+
+// chargeLimitEvent describes the thresholds now in effect, e.g.
+// charge_limit=40-80, or charge_limit=80 for a pack with only an end
+func chargeLimitEvent(src *sysfs.Source) string {
+	for _, c := range src.ChargeControls() {
+		if !c.HasThresholds() {
+			continue
+		}
+		if c.Start < 0 {
+			return fmt.Sprintf("charge_limit=%d", c.End)
+		}
+		return fmt.Sprintf("charge_limit=%d-%d", c.Start, c.End)
+	}
+	return "charge_limit"
+}
+
+// applyChargeSettings reapplies the configured thresholds and behaviour,
+// logging an event only for settings that differ from the firmware's.
+func applyChargeSettings(cfg config.Config, logPath string, pd *profileDaemon) error {
+	src := sysfs.NewSource(cfg.SysfsRoot)
+	controls := src.ChargeControls()
+
+	changed, err := src.ApplyChargeThresholds(cfg.ChargeStartThreshold, cfg.ChargeEndThreshold)
+	if err != nil {
+		return err
+	}
+	if changed {
+		logChargeEvent(cfg, logPath, pd, chargeLimitEvent(src))
+	}
+
+	if cfg.ChargeBehaviour != "" {
+		changed := false
+		for _, c := range controls {
+			if len(c.Behaviours) > 0 && c.Behaviour != cfg.ChargeBehaviour {
+				changed = true
+			}
+		}
+		if changed {
+			if err := src.SetChargeBehaviour(cfg.ChargeBehaviour); err != nil {
+				return err
+			}
+			logChargeEvent(cfg, logPath, pd, "charge_behaviour="+cfg.ChargeBehaviour)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+)
+
+// chargersCmd reports the average charge rate achieved on each charger
+func chargersCmd() {
+	parseFlags("chargers")
+	cfg, logPath := loadPaths()
+	rows, err := readLog(cfg, logPath, time.Time{})
+	if err != nil {
+		log.Fatalf("chargers: %v", err)
+	}
+
+	sessions := analytics.ChargeSessions(rows, cfg.SuspendGapMinutes)
+	stats := analytics.ChargerStats(sessions)
+	if len(stats) == 0 {
+		fmt.Println("no charging sessions in", logPath)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHARGER\tTYPE\tMAX\tSESSIONS\tCHARGING\tRATE\tPOWER")
+	for _, s := range stats {
+		name := s.Charger
+		if name == "" {
+			name = "(unknown)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			name, dash(s.ChargerType), optionalUnit(s.ChargerMaxW, "%.0f W"), s.Sessions,
+			s.Charging.Round(time.Minute), optionalUnit(s.AvgRate, "%.2f %%/min"), optionalUnit(s.AvgPowerW, "%.1f W"))
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+// optionalUnit formats v with format, or "—" when v is NaN
+func optionalUnit(v float64, format string) string {
+	if math.IsNaN(v) {
+		return "—"
+	}
+	return fmt.Sprintf(format, v)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+)
+
+// explainCmd correlates discharge with CPU load to separate workload drain
+// from the hardware baseline
+func explainCmd() {
+	var since time.Duration
+	fs := newFlagSet("explain")
+	fs.DurationVar(&since, "since", 24*time.Hour, "only consider samples from this long ago")
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+
+	cfg, logPath := loadPaths()
+	rows, err := readLog(cfg, logPath, config.Now(cfg).Add(-since))
+	if err != nil {
+		log.Fatalf("explain: %v", err)
+	}
+
+	c, ok := analytics.ExplainDrain(rows, cfg.SuspendGapMinutes)
+	if c.Samples < 2 {
+		fmt.Printf("not enough discharging samples with CPU load in the last %s (cpu_util_pct is logged by the run daemon)\n", since)
+		return
+	}
+
+	fmt.Printf("Discharge vs CPU load, last %s (%d intervals)\n", since, c.Samples)
+	fmt.Printf("  average drain:  %s at %.0f%% CPU\n", formatDrain(c.MeanDrain, c.Unit), c.MeanUtil)
+	if ok {
+		fmt.Printf("  idle baseline:  %s  (hardware: display, radios, idle platform)\n", formatDrain(c.Baseline, c.Unit))
+		fmt.Printf("  per 10%% CPU:    %+.2f %s\n", c.PerPercent*10, c.Unit)
+		fmt.Printf("  workload share: %.0f%% of average drain (fit R² %.2f)\n", c.WorkloadShare()*100, c.R2)
+	} else {
+		fmt.Println("  CPU load did not vary; no correlation to fit")
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CPU LOAD\tINTERVALS\tAVG DRAIN\tAVG FREQ")
+	for _, b := range c.Buckets {
+		if b.Samples == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%.0f-%.0f%%\t%d\t%s\t%s\n", b.Lo, b.Hi, b.Samples,
+			formatDrain(b.MeanDrain, c.Unit), optionalUnit(b.MeanFreqMHz, "%.0f MHz"))
+	}
+	tw.Flush()
+}
+
+func formatDrain(v float64, unit string) string {
+	if math.IsNaN(v) {
+		return "—"
+	}
+	return fmt.Sprintf("%.2f %s", v, unit)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+)
+
+// exportCmd writes the sample log as CSV from the configured store, so a
+// segment store can be read by other tools or imported into a CSV one
+func exportCmd() {
+	var since time.Duration
+	var out, storage string
+	fs := newFlagSet("export")
+	fs.DurationVar(&since, "since", 0, "only export samples from this long ago (0 = all)")
+	fs.StringVar(&out, "o", "", "write to this file instead of stdout")
+	fs.StringVar(&storage, "storage", "", "export from this backend instead of the configured one (csv or segment)")
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+
+	cfg, logPath := loadPaths()
+	if storage != "" {
+		cfg.Storage = storage
+	}
+	store, err := openStore(cfg, logPath)
+	if err != nil {
+		log.Fatalf("export: %v", err)
+	}
+	var from time.Time
+	if since > 0 {
+		from = config.Now(cfg).Add(-since)
+	}
+
+	w := os.Stdout
+	if out != "" {
+		if w, err = os.Create(out); err != nil {
+			log.Fatalf("export: %v", err)
+		}
+		defer w.Close()
+	}
+	bw := bufio.NewWriter(w)
+	n, err := logfile.Export(bw, store, from, time.Time{})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		log.Fatalf("export: %v", err)
+	}
+	if out != "" {
+		fmt.Printf("exported %d rows from %s storage to %s\n", n, cfg.Storage, out)
+	}
+}
+
+// importCmd appends CSV logs or archives to the configured store; with
+// storage = "segment" this converts a CSV history to segments
+func importCmd() {
+	var storage string
+	fs := newFlagSet("import")
+	fs.StringVar(&storage, "storage", "", "import into this backend instead of the configured one (csv or segment)")
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+	if fs.NArg() == 0 {
+		log.Fatalf("import: no files given")
+	}
+
+	cfg, logPath := loadPaths()
+	if storage != "" {
+		cfg.Storage = storage
+	}
+	store, err := openStore(cfg, logPath)
+	if err != nil {
+		log.Fatalf("import: %v", err)
+	}
+	for _, path := range fs.Args() {
+		n, skipped, err := logfile.Import(path, store)
+		if err != nil {
+			log.Fatalf("import %s: %v (%d rows imported)", path, err, n)
+		}
+		fmt.Printf("%s: imported %d rows into %s storage", path, n, cfg.Storage)
+		if skipped > 0 {
+			fmt.Printf(", skipped %d without a readable timestamp", skipped)
+		}
+		fmt.Println()
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/csv"
 	"flag"
 	"fmt"
 	"log"
@@ -10,10 +9,16 @@ import (
 	"time"
 
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/clock"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/health"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/lock"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/peripheral"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/powerprofile"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/procfs"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/uevent"
 )
 
 func main() {
@@ -35,6 +40,30 @@ func main() {
 		statusCmd()
 	case "tui":
 		tuiCmd()
+	case "health":
+		healthCmd()
+	case "charge-limit":
+		chargeLimitCmd()
+	case "charge-behaviour":
+		chargeBehaviourCmd()
+	case "chargers":
+		chargersCmd()
+	case "explain":
+		explainCmd()
+	case "top":
+		topCmd()
+	case "profile":
+		profileCmd()
+	case "suspends":
+		suspendsCmd()
+	case "versions":
+		versionsCmd()
+	case "migrate":
+		migrateCmd()
+	case "export":
+		exportCmd()
+	case "import":
+		importCmd()
 	default:
 		usage()
 	}
@@ -47,6 +76,27 @@ func usage() {
   trim       Force trim to max_lines
   status     Print current reading and path
   tui        Launch interactive TUI for data visualization
+  health     Show battery health and projected wear
+  charge-limit get|set <start> <end>
+             Show or set firmware charge thresholds
+  charge-behaviour [auto|inhibit-charge|force-discharge]
+             Show or set the firmware charge behaviour
+  chargers   Average charge rate per charger
+  explain    Correlate discharge with CPU load (-since 24h)
+  top        Processes using the most battery energy (-since 2h)
+  profile [name]
+             Show or set the platform profile, with drain per profile (-since 168h)
+  suspends   Drain and wakeup sources per suspend, flagging bad nights (-since 168h)
+  versions   Kernel, BIOS and battery model changes, with drain before and after each
+  migrate [file...]
+             Upgrade logs to the current column layout (default: the configured log)
+  export     Write the log as CSV from the configured storage (-since, -o file)
+  import <file...>
+             Append CSV logs or .csv.gz archives to the configured storage
+
+Common flags:
+  -sysfs-root DIR   Read batteries from DIR instead of /sys
+                    (also BATTERY_ZEN_SYSFS_ROOT or sysfs_root in config)
 `)
 	os.Exit(2)
 }
@@ -56,6 +106,9 @@ func loadPaths() (config.Config, string) {
 	if err != nil {
 		log.Fatalf("config: %v", err)
 	}
+	if sysfsRoot != "" {
+		cfg.SysfsRoot = sysfsRoot
+	}
 	logPath, err := config.XDGLogPath(cfg)
 	if err != nil {
 		log.Fatalf("paths: %v", err)
@@ -66,37 +119,165 @@ func loadPaths() (config.Config, string) {
 	return cfg, logPath
 }
 
-func sampleOnce(cfg config.Config, logPath string) error {
-	w := &logfile.Writer{Path: logPath}
-	ac := sysfs.ACOnline()
-	pct, ok := sysfs.BatteryPercent()
+// sampleOnce logs the battery and peripheral levels, returning the latter
+// so the daemon can warn about low devices
+func sampleOnce(cfg config.Config, logPath string, pd *profileDaemon) ([]peripheral.Reading, error) {
+	devices := logPeripherals(cfg)
+	return devices, logSample(cfg, logPath, pd, "")
+}
+
+// logSample appends the current reading, tagged with event if non-empty
+func logSample(cfg config.Config, logPath string, pd *profileDaemon, event string) error {
+	store, err := openStore(cfg, logPath)
+	if err != nil {
+		return err
+	}
+	src := sysfs.NewSource(cfg.SysfsRoot)
+	r, ok := src.Read()
 	if !ok {
 		return fmt.Errorf("battery percent not found")
 	}
-	ts := config.Now(cfg).Format(time.RFC3339)
-	if err := w.AppendCSV(ts, ac, pct); err != nil {
-		return err
+	now := config.Now(cfg)
+	rec := logfile.Record{
+		Timestamp:        now.Format(time.RFC3339),
+		AC:               r.AC,
+		Percent:          r.Percent,
+		Packs:            packLevels(r.Packs),
+		EnergyNow:        r.EnergyNow,
+		EnergyFull:       r.EnergyFull,
+		EnergyFullDesign: r.EnergyFullDesign,
+		Power:            r.PowerNow,
+		Status:           r.Status,
+		CapacityLevel:    r.CapacityLevel,
+		Event:            event,
+		ChargeLimit:      r.ChargeLimit,
+		Charger:          r.Charger.Name,
+		ChargerType:      r.Charger.Kind(),
+		ChargerMaxW:      r.Charger.MaxPowerW,
+		ScreenOn:         r.ScreenOn,
+		IdleSecs:         input.IdleSecs(now),
+		CPUUtil:          math.NaN(),
+		LoadAvg:          math.NaN(),
+		CPUFreqMHz:       math.NaN(),
+		SuspendedSecs:    math.NaN(),
+		UptimeSecs:       math.NaN(),
 	}
-	// Trim if we exceeded threshold
-	lines, err := w.LineCount()
-	if err == nil && lines > (cfg.MaxLines+cfg.TrimBuffer+1) { // +1 header
-		if err := w.TrimToLast(cfg.MaxLines); err != nil {
-			return err
+	proc := procfs.NewSource("")
+	if t, ok := proc.CPUTimes(); ok {
+		rec.CPUUtil = cpuLoad.Update(t)
+	}
+	if v, ok := proc.LoadAvg(); ok {
+		rec.LoadAvg = v
+	}
+	rec.BootID, _ = proc.BootID()
+	rec.LidOpen = -1
+	if open, ok := proc.LidOpen(); ok {
+		rec.LidOpen = 0
+		if open {
+			rec.LidOpen = 1
 		}
 	}
+	rec.ExternalDisplays = -1
+	if n, ok := src.ExternalDisplays(); ok {
+		rec.ExternalDisplays = n
+	}
+	if d, ok := proc.Uptime(); ok {
+		rec.UptimeSecs = d.Seconds()
+	}
+	if v, ok := src.CPUFreqMHz(); ok {
+		rec.CPUFreqMHz = v
+	}
+	rec.PlatformProfile, _ = readPlatformProfile(src, pd)
+	if d, err := clock.Suspended(); err == nil {
+		rec.SuspendedSecs = d.Seconds()
+	}
+	logVersions(cfg, src, now)
+	if err := store.Append(rec); err != nil {
+		return err
+	}
+	logProcesses(cfg, r, now)
+	hs := &health.Store{Path: config.HealthPath(cfg)}
+	if err := hs.Record(healthSnapshots(r.Packs, now)); err != nil {
+		log.Printf("health: %v", err)
+	}
 	return nil
 }
 
+// openStore returns the configured backend of the sample log
+func openStore(cfg config.Config, logPath string) (logfile.Store, error) {
+	return logfile.Open(cfg.Storage, logfile.CSVStore{
+		Path:        logPath,
+		MaxLines:    cfg.MaxLines,
+		TrimBuffer:  cfg.TrimBuffer,
+		Archive:     cfg.ArchiveLogs,
+		Sync:        cfg.SyncWrites,
+		LockTimeout: time.Duration(cfg.LockTimeoutSecs) * time.Second,
+	})
+}
+
+// recoverLog repairs the sample log after an unclean shutdown, e.g. a
+// power loss mid-append, before anything is written to it
+func recoverLog(cfg config.Config, logPath string) {
+	store, err := openStore(cfg, logPath)
+	if err != nil {
+		log.Printf("recover: %v", err)
+		return
+	}
+	rec, err := store.Recover()
+	if err != nil {
+		log.Printf("recover: %v", err)
+		return
+	}
+	if rec.Repaired() {
+		log.Printf("recover: %s", rec)
+	}
+}
+
+// packLevels converts the per-battery readings into log records. A lone
+// pack is omitted since battery_life already carries its level.
+func packLevels(packs []sysfs.Pack) []logfile.PackLevel {
+	if len(packs) < 2 {
+		return nil
+	}
+	levels := make([]logfile.PackLevel, len(packs))
+	for i, p := range packs {
+		levels[i] = logfile.PackLevel{Name: p.Name, Percent: p.Percent}
+	}
+	return levels
+}
+
+// healthSnapshots captures the capacity of each pack for the health history
+func healthSnapshots(packs []sysfs.Pack, t time.Time) []health.Snapshot {
+	snaps := make([]health.Snapshot, 0, len(packs))
+	for _, p := range packs {
+		cycles := -1
+		if p.HasCycleCount {
+			cycles = p.CycleCount
+		}
+		snaps = append(snaps, health.Snapshot{
+			T:                t,
+			Pack:             p.Name,
+			EnergyFull:       p.EnergyFull,
+			EnergyFullDesign: p.EnergyFullDesign,
+			CycleCount:       cycles,
+		})
+	}
+	return snaps
+}
+
 func sampleCmd() {
+	parseFlags("sample")
 	cfg, logPath := loadPaths()
-	if err := sampleOnce(cfg, logPath); err != nil {
+	recoverLog(cfg, logPath)
+	if _, err := sampleOnce(cfg, logPath, &profileDaemon{}); err != nil {
 		log.Fatalf("sample: %v", err)
 	}
 }
 
 func runCmd() {
+	parseFlags("run")
 	cfg, logPath := loadPaths()
-	// Guard with pidfile so only one daemon runs
+	// Hold an flock on the pidfile so only one daemon runs
 	lockPath := cfg.LogDir + "/.battery-zen.pid"
 	pf := &lock.PIDFile{Path: lockPath}
 	ok, err := pf.Acquire()
@@ -107,62 +288,281 @@ func runCmd() {
 		log.Fatalf("another instance is running")
 	}
 	defer pf.Release()
+	recoverLog(cfg, logPath)
+
+	// power-profiles-daemon is looked up once, not on every sample
+	pd := &profileDaemon{}
+
+	if cfg.ApplyChargeSettings {
+		if err := applyChargeSettings(cfg, logPath, pd); err != nil {
+			log.Printf("charge settings: %v", err)
+		}
+	}
 
 	interval := time.Duration(cfg.IntervalSecs) * time.Second
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	// Initial tick immediately
-	if err := sampleOnce(cfg, logPath); err != nil {
-		log.Printf("sample: %v", err)
+	// Sample immediately on plug/unplug; polling stays as the fallback
+	events := make(chan uevent.Event, 16)
+	if src, err := uevent.Listen(); err != nil {
+		log.Printf("uevent: %v (polling only)", err)
+	} else {
+		defer src.Close()
+		go pumpEvents(src, events)
+	}
+
+	// Wakeup counts are taken as logind announces each suspend and resume
+	var sleeps <-chan bool
+	sleep := watchSleep()
+	if sleep != nil {
+		defer sleep.Close()
+		sleeps = sleep.C
 	}
 
-	for range ticker.C {
-		if err := sampleOnce(cfg, logPath); err != nil {
+	monitor := &peripheral.Monitor{Threshold: cfg.PeripheralLowPercent}
+	profiles := &powerprofile.Switcher{Rules: profileRules(cfg)}
+	sample := func() {
+		devices, err := sampleOnce(cfg, logPath, pd)
+		if err != nil {
 			log.Printf("sample: %v", err)
 		}
+		for _, d := range monitor.Check(devices) {
+			log.Printf("warning: %s", monitor.Warning(d))
+		}
+		if !profiles.Rules.Empty() {
+			switchProfile(cfg, logPath, pd, profiles)
+		}
+	}
+
+	// Input activity is polled between samples so idle_secs is accurate to
+	// a few seconds; one-shot samples leave it empty
+	var inputTick <-chan time.Time
+	proc := procfs.NewSource("")
+	if n, ok := proc.InputInterrupts(); ok {
+		input.Observe(n, time.Now())
+		t := time.NewTicker(inputPollInterval)
+		defer t.Stop()
+		inputTick = t.C
+	} else {
+		log.Printf("input: no keyboard/touchpad interrupts in /proc/interrupts (idle_secs not logged)")
+	}
+
+	// Initial tick immediately
+	sample()
+
+	// A plug-in emits events for the adapter and every pack within a few
+	// milliseconds, and sysfs may lag the event; settle before sampling.
+	const settle = 500 * time.Millisecond
+	// Start from the state the initial sample saw, so the first periodic
+	// event of each supply does not trigger another
+	var tracker uevent.Tracker
+	for name, st := range sysfs.NewSource(cfg.SysfsRoot).SupplyStates() {
+		tracker.Seed(name, st.Online, st.Status)
+	}
+	var pending <-chan time.Time
+
+	for {
+		select {
+		case <-ticker.C:
+		case now := <-inputTick:
+			if n, ok := proc.InputInterrupts(); ok {
+				input.Observe(n, now)
+			}
+			continue
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if tracker.Changed(ev) && pending == nil {
+				pending = time.After(settle)
+			}
+			continue
+		case sleeping, ok := <-sleeps:
+			if !ok {
+				sleeps = nil
+				continue
+			}
+			onSleep(cfg, sleep, sleeping)
+			continue
+		case <-pending:
+			pending = nil
+			ticker.Reset(interval)
+		}
+		sample()
+	}
+}
+
+// input tracks keyboard/touchpad activity while the daemon runs
+var input procfs.InputTracker
+
+// cpuLoad measures CPU utilisation between consecutive samples; the first
+// sample of a process has no utilisation
+var cpuLoad procfs.CPUTracker
+
+const inputPollInterval = 5 * time.Second
+
+// pumpEvents forwards uevents to ch until the source fails or is closed
+func pumpEvents(src uevent.Source, ch chan<- uevent.Event) {
+	defer close(ch)
+	for {
+		ev, err := src.Receive()
+		if err != nil {
+			log.Printf("uevent: %v (polling only)", err)
+			return
+		}
+		ch <- ev
 	}
 }
 
 func trimCmd() {
+	parseFlags("trim")
 	cfg, logPath := loadPaths()
-	w := &logfile.Writer{Path: logPath}
-	if err := w.TrimToLast(cfg.MaxLines); err != nil {
+	store, err := openStore(cfg, logPath)
+	if err != nil {
+		log.Fatalf("trim: %v", err)
+	}
+	cs, ok := store.(*logfile.CSVStore)
+	if !ok {
+		fmt.Printf("%s storage keeps all history; nothing to trim\n", cfg.Storage)
+		return
+	}
+	if err := cs.Trim(); err != nil {
 		log.Fatalf("trim: %v", err)
 	}
 }
 
 func statusCmd() {
+	parseFlags("status")
 	cfg, logPath := loadPaths()
-	ac := sysfs.ACOnline()
-	pct, _ := sysfs.BatteryPercent()
+	src := sysfs.NewSource(cfg.SysfsRoot)
+	r, _ := src.Read()
+	if len(r.Packs) == 0 {
+		r.AC = src.ACOnline()
+	}
 	fmt.Printf("ac_connected=%t battery_life=%d ts=%s file=%s\n",
-		ac, pct, config.Now(cfg).Format(time.RFC3339), logPath)
+		r.AC, r.Percent, config.Now(cfg).Format(time.RFC3339), logPath)
+	if r.Charger.Name != "" {
+		fmt.Printf("charger=%s charger_type=%s charger_max_w=%.1f\n", r.Charger.Name, r.Charger.Kind(), r.Charger.MaxPowerW)
+	}
+	fmt.Printf("status=%q capacity_level=%s energy_now_wh=%.2f energy_full_wh=%.2f power_w=%.2f\n",
+		r.Status, r.CapacityLevel, r.EnergyNow, r.EnergyFull, r.PowerNow)
+	if on, ok := src.ScreenOn(); ok {
+		fmt.Printf("screen_on=%t\n", on)
+	}
+	if open, ok := procfs.NewSource("").LidOpen(); ok {
+		fmt.Printf("lid_open=%t\n", open)
+	}
+	if n, ok := src.ExternalDisplays(); ok {
+		fmt.Printf("external_displays=%d\n", n)
+	}
+	if p, ok := readPlatformProfile(src, &profileDaemon{}); ok {
+		fmt.Printf("platform_profile=%s\n", p)
+	}
+	if len(r.Packs) > 1 {
+		fmt.Printf("batteries=%s\n", logfile.FormatPacks(packLevels(r.Packs)))
+	}
+	for _, d := range readPeripherals(cfg, config.Now(cfg)) {
+		fmt.Printf("peripheral=%q device=%s source=%s percent=%.0f\n", d.Label(), d.Device, d.Source, d.Percent)
+	}
+	printBootSessions(cfg, logPath)
 }
 
-// optional flags example (not strictly needed):
-func init() {
-	if len(os.Args) > 1 && os.Args[1] == "run" {
-		fs := flag.NewFlagSet("run", flag.ExitOnError)
-		_ = fs // add overrides if you wish
+// statusBootSessions is how many recent boots status lists, from at most
+// statusBootWindow of history
+const (
+	statusBootSessions = 5
+	statusBootWindow   = 30 * 24 * time.Hour
+)
+
+// printBootSessions lists the current boot and the battery used during the
+// most recent logged boots
+func printBootSessions(cfg config.Config, logPath string) {
+	proc := procfs.NewSource("")
+	if id, ok := proc.BootID(); ok {
+		uptime, _ := proc.Uptime()
+		fmt.Printf("boot_id=%s uptime=%s\n", id, uptime.Round(time.Second))
+	}
+	rows, err := readLog(cfg, logPath, config.Now(cfg).Add(-statusBootWindow))
+	if err != nil {
+		return
+	}
+	sessions := analytics.BootSessions(rows, cfg.SuspendGapMinutes)
+	if len(sessions) > statusBootSessions {
+		sessions = sessions[len(sessions)-statusBootSessions:]
+	}
+	for _, b := range sessions {
+		fmt.Printf("boot_session=%s boot=%s end=%s awake=%s asleep=%s used_pct=%.0f charged_pct=%.0f",
+			b.BootID, b.Boot.Format(time.RFC3339), b.End.Format(time.RFC3339), b.Awake().Round(time.Minute),
+			b.Asleep.Round(time.Minute), b.Used, b.Charged)
+		if !math.IsNaN(b.UsedWh) {
+			fmt.Printf(" used_wh=%.1f", b.UsedWh)
+		}
+		fmt.Println()
 	}
 }
 
-// readCSV reads the battery CSV file and parses it into Row structs
-func readCSV(path string) ([]analytics.Row, error) {
-	f, err := os.Open(path)
+func healthCmd() {
+	parseFlags("health")
+	cfg, _ := loadPaths()
+	hs := &health.Store{Path: config.HealthPath(cfg)}
+	snaps, err := hs.Load()
 	if err != nil {
-		return nil, err
+		log.Fatalf("health: %v", err)
+	}
+	// Record the live reading so a fresh install reports something
+	if r, ok := sysfs.NewSource(cfg.SysfsRoot).Read(); ok {
+		live := healthSnapshots(r.Packs, config.Now(cfg))
+		if err := hs.Record(live); err == nil {
+			snaps, _ = hs.Load()
+		}
+	}
+	packs := health.Packs(snaps)
+	if len(packs) == 0 {
+		fmt.Println("no battery health data (battery does not report energy_full/energy_full_design)")
+		return
+	}
+	fmt.Printf("file=%s\n", hs.Path)
+	for _, name := range packs {
+		tr := health.FitTrend(snaps, name, float64(cfg.HealthTarget))
+		fmt.Println(tr.Summary())
+		fmt.Printf("  trend: %s\n", tr.Projection())
 	}
-	defer f.Close()
+}
+
+// sysfsRoot is set by -sysfs-root and overrides the environment and config
+var sysfsRoot string
 
-	r := csv.NewReader(f)
-	r.FieldsPerRecord = -1
-	rows, err := r.ReadAll()
+// newFlagSet returns a flag set with the flags shared by all commands
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&sysfsRoot, "sysfs-root", "", "read batteries from this sysfs tree instead of /sys")
+	return fs
+}
+
+// parseFlags parses the shared flags for commands without flags of their
+// own and returns the remaining positional arguments
+func parseFlags(name string) []string {
+	fs := newFlagSet(name)
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+	return fs.Args()
+}
+
+// readLog reads the battery log from since onwards (everything for a zero
+// since) from the configured store and parses it into Row structs. The CSV
+// store spans the monthly archives and the live file.
+func readLog(cfg config.Config, path string, since time.Time) ([]analytics.Row, error) {
+	store, err := openStore(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := store.Range(since, time.Time{})
 	if err != nil {
 		return nil, err
 	}
-
 	return analytics.ParseCSVRows(rows)
 }
 
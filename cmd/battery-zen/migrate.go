@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+)
+
+// migrateCmd rewrites sample logs in the current schema. It takes the
+// files to upgrade and defaults to the configured log.
+func migrateCmd() {
+	paths := parseFlags("migrate")
+	if len(paths) == 0 {
+		_, logPath := loadPaths()
+		paths = []string{logPath}
+	}
+	for _, path := range paths {
+		m, err := (&logfile.Writer{Path: path}).Migrate()
+		if err != nil {
+			log.Fatalf("migrate %s: %v", path, err)
+		}
+		if !m.Changed() {
+			fmt.Printf("%s: schema %d, up to date (%d rows)\n", path, m.From, m.Rows)
+			continue
+		}
+		fmt.Printf("%s: schema %d -> %d, %d rows kept\n", path, m.From, logfile.SchemaVersion, m.Rows)
+		if len(m.Renamed) > 0 {
+			fmt.Printf("  renamed: %s\n", strings.Join(m.Renamed, ", "))
+		}
+		if len(m.Added) > 0 {
+			fmt.Printf("  added: %s\n", strings.Join(m.Added, ", "))
+		}
+	}
+}
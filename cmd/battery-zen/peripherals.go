@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/bluez"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/peripheral"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+
+	"github.com/godbus/dbus/v5"
+)
+
+var (
+	busOnce sync.Once
+	bus     *dbus.Conn
+)
+
+// systemBus connects to the system bus on first use. Without one only
+// sysfs peripherals and the sysfs platform profile are available.
+func systemBus() *dbus.Conn {
+	busOnce.Do(func() {
+		conn, err := bluez.Connect("")
+		if err != nil {
+			log.Printf("dbus: %v (no BlueZ peripherals, power-profiles-daemon or wakeup sources per suspend)", err)
+			return
+		}
+		bus = conn
+	})
+	return bus
+}
+
+// readPeripherals collects the current peripheral levels from sysfs and BlueZ
+func readPeripherals(cfg config.Config, now time.Time) []peripheral.Reading {
+	var bt []bluez.Battery
+	if conn := systemBus(); conn != nil {
+		var err error
+		if bt, err = bluez.Batteries(conn); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	return peripheral.Collect(sysfs.NewSource(cfg.SysfsRoot).Peripherals(), bt, now)
+}
+
+// logPeripherals appends the current peripheral levels to their own log
+func logPeripherals(cfg config.Config) []peripheral.Reading {
+	readings := readPeripherals(cfg, config.Now(cfg))
+	ps := &peripheral.Store{Path: config.PeripheralPath(cfg)}
+	if err := ps.Append(readings); err != nil {
+		log.Printf("peripherals: %v", err)
+		return readings
+	}
+	if err := ps.Trim(cfg.MaxLines, cfg.TrimBuffer); err != nil {
+		log.Printf("peripherals: %v", err)
+	}
+	return readings
+}
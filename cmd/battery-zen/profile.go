@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/powerprofile"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+)
+
+// powerProfiles returns the running power-profiles-daemon, or nil
+func powerProfiles() *powerprofile.Daemon {
+	conn := systemBus()
+	if conn == nil {
+		return nil
+	}
+	d, err := powerprofile.Find(conn)
+	if err != nil {
+		log.Printf("profile: %v", err)
+	}
+	return d
+}
+
+// profileDaemon finds power-profiles-daemon on first use and keeps it, so
+// samples do not ask the bus each time. After a call to it fails the next
+// use looks again, as the daemon may have restarted or gone.
+type profileDaemon struct {
+	d      *powerprofile.Daemon
+	looked bool
+}
+
+func (p *profileDaemon) get() *powerprofile.Daemon {
+	if !p.looked {
+		p.d, p.looked = powerProfiles(), true
+	}
+	return p.d
+}
+
+// readPlatformProfile returns the active platform profile from sysfs, or
+// from power-profiles-daemon on machines without ACPI platform profiles
+func readPlatformProfile(src *sysfs.Source, pd *profileDaemon) (string, bool) {
+	if p, _, ok := src.PlatformProfile(); ok {
+		return p, true
+	}
+	if d := pd.get(); d != nil {
+		p, err := d.Active()
+		if err != nil {
+			log.Printf("profile: %v", err)
+			pd.looked = false
+			return "", false
+		}
+		return p, true
+	}
+	return "", false
+}
+
+// setPlatformProfile switches through power-profiles-daemon when it runs,
+// since it owns platform_profile then, and writes sysfs otherwise. Either
+// daemon or ACPI names are accepted.
+func setPlatformProfile(src *sysfs.Source, pd *profileDaemon, profile string) error {
+	if d := pd.get(); d != nil {
+		if err := d.Set(profile); err != nil {
+			pd.looked = false
+			return err
+		}
+		return nil
+	}
+	return src.SetPlatformProfile(powerprofile.Normalize(profile))
+}
+
+// profileRules builds the daemon's switching rules from the config
+func profileRules(cfg config.Config) powerprofile.Rules {
+	return powerprofile.Rules{
+		OnAC:       cfg.ProfileOnAC,
+		OnBattery:  cfg.ProfileOnBattery,
+		Low:        cfg.ProfileLowBattery,
+		LowPercent: float64(cfg.ProfileLowBatteryAt),
+	}
+}
+
+// switchProfile applies the rules to the current reading, logging an event
+// when the profile changes
+func switchProfile(cfg config.Config, logPath string, pd *profileDaemon, sw *powerprofile.Switcher) {
+	src := sysfs.NewSource(cfg.SysfsRoot)
+	r, ok := src.Read()
+	if !ok {
+		return
+	}
+	current, _ := readPlatformProfile(src, pd)
+	next, ok := sw.Next(r.AC, float64(r.Percent), current)
+	if !ok {
+		return
+	}
+	if err := setPlatformProfile(src, pd, next); err != nil {
+		log.Printf("profile: %v", err)
+		return
+	}
+	logChargeEvent(cfg, logPath, pd, "platform_profile="+powerprofile.Normalize(next))
+}
+
+// profileCmd implements "profile [name]": it shows the active profile and
+// the average drain under each logged profile, or switches profiles
+func profileCmd() {
+	var since time.Duration
+	fs := newFlagSet("profile")
+	fs.DurationVar(&since, "since", 7*24*time.Hour, "only consider samples from this long ago")
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+	cfg, logPath := loadPaths()
+	src := sysfs.NewSource(cfg.SysfsRoot)
+	pd := &profileDaemon{}
+
+	switch args := fs.Args(); len(args) {
+	case 0:
+	case 1:
+		if err := setPlatformProfile(src, pd, args[0]); err != nil {
+			log.Fatalf("profile: %v", err)
+		}
+		logChargeEvent(cfg, logPath, pd, "platform_profile="+powerprofile.Normalize(args[0]))
+	default:
+		fmt.Fprintln(os.Stderr, "usage: battery-zen profile [-since 168h] [name]")
+		os.Exit(2)
+	}
+
+	if p, choices, ok := src.PlatformProfile(); ok {
+		fmt.Printf("platform profile: %s (available: %s)\n", p, strings.Join(choices, ", "))
+	} else if p, ok := readPlatformProfile(src, pd); ok {
+		fmt.Printf("platform profile: %s (power-profiles-daemon)\n", p)
+	} else {
+		fmt.Println("platform profile: not supported")
+	}
+
+	rows, err := readLog(cfg, logPath, config.Now(cfg).Add(-since))
+	if err != nil {
+		log.Fatalf("profile: %v", err)
+	}
+	drains, unit := analytics.DrainByProfile(rows, cfg.SuspendGapMinutes)
+	if len(drains) == 0 {
+		fmt.Printf("no discharging samples with a platform profile in the last %s\n", since)
+		return
+	}
+
+	fmt.Printf("\nDischarge by profile, last %s\n", since)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tON BATTERY\tAVG DRAIN\tAVG CPU\tVS "+strings.ToUpper(drains[0].Profile))
+	for _, d := range drains {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Profile, d.Duration.Round(time.Minute),
+			formatDrain(d.MeanDrain, unit), optionalUnit(d.MeanUtil, "%.0f%%"),
+			relativeDrain(d.MeanDrain, drains[0].MeanDrain))
+	}
+	tw.Flush()
+}
+
+// relativeDrain formats v as a percentage change from base
+func relativeDrain(v, base float64) string {
+	if !(base > 0) || math.IsNaN(v) {
+		return "—"
+	}
+	return fmt.Sprintf("%+.0f%%", (v/base-1)*100)
+}
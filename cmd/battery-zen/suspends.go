@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/clock"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logind"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/wakeup"
+)
+
+// wakeups diffs wakeup source counts across each suspend while the daemon
+// runs
+var wakeups wakeup.Tracker
+
+// watchSleep follows logind's suspend announcements, or returns nil when
+// there is no system bus or no logind
+func watchSleep() *logind.SleepWatcher {
+	conn := systemBus()
+	if conn == nil {
+		return nil
+	}
+	w, err := logind.WatchSleep(conn, "Record the wakeup sources of the suspend")
+	if err != nil {
+		log.Printf("%v (no wakeup sources per suspend)", err)
+		return nil
+	}
+	return w
+}
+
+// onSleep snapshots the wakeup source counts as the system goes to sleep,
+// letting the suspend proceed once they are taken, and records which
+// sources fired once it resumes
+func onSleep(cfg config.Config, w *logind.SleepWatcher, sleeping bool) {
+	if sleeping {
+		defer w.Release()
+	} else if err := w.Inhibit(); err != nil {
+		log.Printf("wakeups: %v", err)
+	}
+	suspended, err := clock.Suspended()
+	if err != nil {
+		log.Printf("wakeups: %v", err)
+		return
+	}
+	counts := sysfs.NewSource(cfg.SysfsRoot).WakeupCounts()
+	if sleeping {
+		wakeups.Suspend(counts, suspended, config.Now(cfg))
+		return
+	}
+	rec, ok := wakeups.Resume(counts, suspended, config.Now(cfg))
+	if !ok {
+		return
+	}
+	if err := (&wakeup.Store{Path: config.SuspendPath(cfg)}).Append(rec); err != nil {
+		log.Printf("wakeups: %v", err)
+	}
+}
+
+// suspendWakeupsShown is how many wakeup sources the report lists per suspend
+const suspendWakeupsShown = 3
+
+// suspendsCmd lists each suspend with its drain rate and the wakeup sources
+// that fired, flagging nights that drained unusually fast
+func suspendsCmd() {
+	var since time.Duration
+	fs := newFlagSet("suspends")
+	fs.DurationVar(&since, "since", 7*24*time.Hour, "only consider samples from this long ago")
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+
+	cfg, logPath := loadPaths()
+	rows, err := readLog(cfg, logPath, config.Now(cfg).Add(-since))
+	if err != nil {
+		log.Fatalf("suspends: %v", err)
+	}
+	records, err := (&wakeup.Store{Path: config.SuspendPath(cfg)}).Load()
+	if err != nil {
+		log.Fatalf("suspends: %v", err)
+	}
+
+	drains, median := analytics.SuspendDrains(analytics.DetectSuspendEvents(rows, cfg.SuspendGapMinutes), cfg.AbnormalDrainFactor)
+	if len(drains) == 0 {
+		fmt.Printf("no suspends in the last %s\n", since)
+		return
+	}
+
+	fmt.Printf("Suspends, last %s", since)
+	if !math.IsNaN(median) {
+		fmt.Printf(" (median night %.2f %%/h; ! = over %.1f× that)", median, cfg.AbnormalDrainFactor)
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tSTART\tKIND\tASLEEP\tDRAIN\tRATE\tWAKEUPS")
+	for _, d := range drains {
+		flag := ""
+		if d.Abnormal {
+			flag = "!"
+		}
+		wakes := "—"
+		if rec, ok := wakeup.Find(records, d.StartTime, d.EndTime); ok {
+			wakes = formatWakeups(rec.Wakeups)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.0f%%\t%.2f %%/h\t%s\n", flag,
+			d.StartTime.Format("Jan 2 15:04"), d.Kind, d.Duration.Round(time.Minute),
+			d.BatteryDrop, d.RatePerHour, wakes)
+	}
+	tw.Flush()
+}
+
+// formatWakeups lists the busiest wakeup sources as "rtc0×3, XHC×1"
+func formatWakeups(counts []wakeup.Count) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	var parts []string
+	for i, c := range counts {
+		if i == suspendWakeupsShown {
+			parts = append(parts, fmt.Sprintf("+%d more", len(counts)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s×%d", c.Name, c.Events))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/clock"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/consumers"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/procfs"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+)
+
+// processesPerInterval is how many processes each interval keeps by name;
+// the rest are summed into consumers.Other
+const processesPerInterval = 10
+
+var (
+	procs      procfs.ProcessTracker
+	procsClock consumers.Clock
+)
+
+// logProcesses appends the CPU time each process used since the previous
+// sample, with the battery drain to attribute over the time awake. The
+// first call only records a baseline, so one-shot samples log nothing.
+func logProcesses(cfg config.Config, r sysfs.Reading, now time.Time) {
+	table, err := procfs.NewSource("").Processes()
+	if err != nil {
+		log.Printf("processes: %v", err)
+		return
+	}
+	cpu, ok := procs.Update(table)
+	// Without the clock the interval falls back to wall-clock time
+	suspended, _ := clock.Suspended()
+	awake, lapped := procsClock.Lap(now, suspended)
+	if !ok || !lapped {
+		return
+	}
+
+	drain := math.NaN()
+	if !r.AC {
+		drain = r.PowerNow
+	}
+	iv := consumers.NewInterval(now, awake, r.AC, drain, cpu, processesPerInterval)
+	cs := &consumers.Store{Path: config.ProcessPath(cfg)}
+	if err := cs.Append(iv); err != nil {
+		log.Printf("processes: %v", err)
+		return
+	}
+	if err := cs.Trim(cfg.MaxLines, cfg.TrimBuffer); err != nil {
+		log.Printf("processes: %v", err)
+	}
+}
+
+// topCmd lists the processes that used the most battery energy
+func topCmd() {
+	var since time.Duration
+	var n int
+	fs := newFlagSet("top")
+	fs.DurationVar(&since, "since", 2*time.Hour, "only consider intervals from this long ago")
+	fs.IntVar(&n, "n", 15, "number of processes to show")
+	if len(os.Args) > 2 {
+		fs.Parse(os.Args[2:])
+	}
+
+	cfg, logPath := loadPaths()
+	// Without the main log, intervals lacking a power reading are skipped
+	start := config.Now(cfg).Add(-since)
+	rows, _ := readLog(cfg, logPath, start)
+	list, total, err := consumers.Report(config.ProcessPath(cfg), rows, start)
+	if err != nil {
+		log.Fatalf("top: %v", err)
+	}
+	if len(list) == 0 {
+		fmt.Printf("no process data in the last %s (recorded by the run daemon)\n", since)
+		return
+	}
+
+	fmt.Printf("Top consumers, last %s: %.2f Wh of battery drain attributed by CPU share\n\n", since, total)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROCESS\tENERGY\tSHARE\tCPU TIME")
+	for i, c := range list {
+		if i >= n {
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%.2f Wh\t%.0f%%\t%s\n", c.Name, c.EnergyWh, c.Share*100,
+			time.Duration(c.CPUSecs*float64(time.Second)).Round(time.Second))
+	}
+	tw.Flush()
+}
@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
-	"flag"
 	"log"
 	"os"
 	"time"
 
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/tui"
 
 	"github.com/mum4k/termdash"
@@ -17,7 +18,7 @@ import (
 func runTUI() {
 	var alpha float64
 
-	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	fs := newFlagSet("tui")
 	fs.Float64Var(&alpha, "alpha", 0.05, "exponential decay per minute for weights (e.g., 0.05)")
 
 	if len(os.Args) > 2 {
@@ -49,11 +50,21 @@ func runTUI() {
 
 	sotBarChart := tui.CreateSOTBarChart()
 
+	healthWidget, err := tui.CreateTextWidget()
+	if err != nil {
+		log.Fatalf("CreateTextWidget => %v", err)
+	}
+
+	consumerWidget, err := tui.CreateTextWidget()
+	if err != nil {
+		log.Fatalf("CreateTextWidget => %v", err)
+	}
+
 	// Data update function (declared here so it can be used in callbacks)
 	var updateData func() error
 
 	// Set up the container with layout
-	c, err := tui.CreateUILayout(t, chartWidget, textWidget, sotBarChart)
+	c, err := tui.CreateUILayout(t, chartWidget, textWidget, sotBarChart, healthWidget, consumerWidget)
 	if err != nil {
 		log.Fatalf("CreateUILayout => %v", err)
 	}
@@ -67,7 +78,9 @@ func runTUI() {
 	defer cancel()
 
 	// Set up data refresh and get the update function
-	updateData, err = tui.SetupDataRefresh(ctx, logPath, uiParams, chartWidget, textWidget, sotBarChart, cfg, c, alpha, readCSV)
+	updateData, err = tui.SetupDataRefresh(ctx, logPath, uiParams, chartWidget, textWidget, sotBarChart, healthWidget, consumerWidget, cfg, c, alpha, func(path string) ([]analytics.Row, error) {
+		return readLog(cfg, path, config.Now(cfg).AddDate(0, 0, -tuiHistoryDays(cfg)))
+	})
 	if err != nil {
 		log.Fatalf("SetupDataRefresh => %v", err)
 	}
@@ -78,7 +91,7 @@ func runTUI() {
 	}
 
 	// Create keyboard event handler
-	keyboardHandler := tui.CreateKeyboardHandler(cancel, updateData)
+	keyboardHandler := tui.CreateKeyboardHandler(cancel, uiParams, updateData)
 
 	// Run the dashboard
 	currentRefresh := uiParams.Get()
@@ -86,3 +99,9 @@ func runTUI() {
 		log.Fatalf("termdash.Run => %v", err)
 	}
 }
+
+// tuiHistoryDays is how much of the log the TUI reads: the widest zoom
+// window, and at least the week of the SOT bar chart
+func tuiHistoryDays(cfg config.Config) int {
+	return max(cfg.MaxWindowZoom, tui.SOTDays)
+}
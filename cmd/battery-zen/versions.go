@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/procfs"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/versions"
+)
+
+// readVersions returns the running kernel, BIOS and battery models by
+// component name. Components that cannot be read are left out.
+func readVersions(src *sysfs.Source) map[string]string {
+	seen := make(map[string]string)
+	if v, ok := procfs.NewSource("").KernelRelease(); ok {
+		seen[versions.Kernel] = v
+	}
+	if v, ok := src.BIOSVersion(); ok {
+		seen[versions.BIOS] = v
+	}
+	for _, m := range src.BatteryModels() {
+		seen[versions.Battery(m.Pack)] = m.String()
+	}
+	return seen
+}
+
+// logVersions records the kernel, BIOS and battery models when any changed
+// since the last sample
+func logVersions(cfg config.Config, src *sysfs.Source, now time.Time) {
+	vs := &versions.Store{Path: config.VersionPath(cfg)}
+	changes, err := vs.Record(readVersions(src), now)
+	if err != nil {
+		log.Printf("versions: %v", err)
+		return
+	}
+	for _, c := range changes {
+		if c.Upgrade() {
+			log.Printf("%s changed: %s -> %s", c.Component, c.From, c.To)
+		}
+	}
+}
+
+// versionsCmd lists version changes and compares drain before and after
+// each, flagging significant regressions
+func versionsCmd() {
+	parseFlags("versions")
+	cfg, logPath := loadPaths()
+
+	changes, err := (&versions.Store{Path: config.VersionPath(cfg)}).Load()
+	if err != nil {
+		log.Fatalf("versions: %v", err)
+	}
+	if len(changes) == 0 {
+		fmt.Println("no version history yet (the run daemon and sample record it)")
+		return
+	}
+	fmt.Printf("file=%s\n", config.VersionPath(cfg))
+	since := make(map[string]time.Time)
+	for _, c := range changes {
+		since[c.Component] = c.T
+	}
+	cur := versions.Current(changes)
+	names := make([]string, 0, len(cur))
+	for name := range cur {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s=%q since=%s\n", name, cur[name], since[name].Format(time.RFC3339))
+	}
+
+	var upgrades []versions.Change
+	for _, c := range changes {
+		if c.Upgrade() {
+			upgrades = append(upgrades, c)
+		}
+	}
+	if len(upgrades) == 0 {
+		fmt.Println("no version changes logged yet")
+		return
+	}
+	window := time.Duration(cfg.RegressionWindowDays) * 24 * time.Hour
+	rows, err := readLog(cfg, logPath, upgrades[0].T.Add(-window))
+	if err != nil {
+		log.Fatalf("versions: %v", err)
+	}
+
+	fmt.Printf("\nDrain %d days either side of each change (! = significant rise, p < %.2f)\n",
+		cfg.RegressionWindowDays, analytics.RegressionAlpha)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tDATE\tCOMPONENT\tCHANGE\tDISCHARGE\tSUSPEND")
+	for _, c := range upgrades {
+		start, end := changeWindow(changes, c.T, window)
+		v := analytics.CompareDrainAt(rowsBetween(rows, start, end), c.T, cfg.SuspendGapMinutes)
+		flag := ""
+		if v.Regression() {
+			flag = "!"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s → %s\t%s\t%s\n", flag, c.T.Format("Jan 2 2006"), c.Component,
+			c.From, c.To, formatComparison(v.Discharge), formatComparison(v.Suspend))
+	}
+	tw.Flush()
+}
+
+// changeWindow bounds the rows compared around a change at t to window on
+// either side, stopping at the neighbouring changes so each side runs a
+// single set of versions
+func changeWindow(changes []versions.Change, t time.Time, window time.Duration) (time.Time, time.Time) {
+	start, end := t.Add(-window), t.Add(window)
+	for _, c := range changes {
+		if c.T.Before(t) && c.T.After(start) {
+			start = c.T
+		}
+		if c.T.After(t) && c.T.Before(end) {
+			end = c.T
+		}
+	}
+	return start, end
+}
+
+// rowsBetween returns the rows at or after start and before end
+func rowsBetween(rows []analytics.Row, start, end time.Time) []analytics.Row {
+	rows = analytics.RowsSince(rows, start)
+	for i, r := range rows {
+		if !r.T.Before(end) {
+			return rows[:i]
+		}
+	}
+	return rows
+}
+
+// formatComparison shows a drain comparison as "8.00 → 11.00 W (+38%, p=0.001)"
+func formatComparison(c analytics.DrainComparison) string {
+	if c.Before.N == 0 || c.After.N == 0 {
+		return "—"
+	}
+	var notes []string
+	if ch := c.Change(); !math.IsNaN(ch) {
+		notes = append(notes, fmt.Sprintf("%+.0f%%", ch*100))
+	}
+	switch {
+	case math.IsNaN(c.PValue):
+		notes = append(notes, fmt.Sprintf("n=%d/%d", c.Before.N, c.After.N))
+	case c.PValue < 0.001:
+		notes = append(notes, "p<0.001")
+	default:
+		notes = append(notes, fmt.Sprintf("p=%.3f", c.PValue))
+	}
+	return fmt.Sprintf("%.2f → %.2f %s (%s)", c.Before.Mean, c.After.Mean, c.Unit, strings.Join(notes, ", "))
+}
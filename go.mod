@@ -2,7 +2,11 @@ module github.com/Prajwal-Prathiksh/battery-zen
 
 go 1.22
 
-require github.com/mum4k/termdash v0.20.0
+require (
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/mum4k/termdash v0.20.0
+	golang.org/x/sys v0.17.0
+)
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
@@ -10,7 +14,6 @@ require (
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
-	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
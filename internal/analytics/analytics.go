@@ -7,12 +7,52 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
 )
 
 // Row represents a single CSV record
 type Row struct {
-	T    time.Time
-	AC   bool
+	T     time.Time
+	AC    bool
+	Batt  float64       // Combined level of all packs
+	Packs []PackReading // Per-battery breakdown, empty for single-pack logs
+
+	// Power telemetry; NaN (or empty strings) for older three-column logs
+	EnergyNow        float64 // Wh
+	EnergyFull       float64 // Wh
+	EnergyFullDesign float64 // Wh
+	PowerW           float64 // W
+	Status           string
+	State            State // Parsed from Status, StateUnknown for older logs
+	CapacityLevel    string
+	Event            string // Non-empty on rows recording a settings change
+	ChargeLimit      int    // Firmware charge end threshold, -1 if none or unknown
+	Charger          string // Active external supply name, empty on battery or old logs
+	ChargerType      string // e.g. Mains, USB/PD
+	ChargerMaxW      float64
+	ScreenOn         int     // 1 on, 0 off, -1 unknown (logs without screen_on)
+	IdleSecs         int     // Seconds since the last input, -1 unknown
+	CPUUtil          float64 // % busy over the interval ending at this sample
+	LoadAvg          float64
+	CPUFreqMHz       float64
+	Profile          string  // Platform profile, empty if unknown
+	SuspendedSecs    float64 // Cumulative time suspended since boot, NaN if not logged
+	BootID           string  // Kernel boot ID, empty if not logged
+	UptimeSecs       float64 // Time since boot including suspend, NaN if not logged
+	LidOpen          int     // 1 open, 0 closed, -1 unknown
+	ExternalDisplays int     // Connected external outputs, -1 unknown
+}
+
+// Docked reports whether the laptop ran closed with an external display,
+// i.e. clamshell mode at a desk
+func (r Row) Docked() bool {
+	return r.LidOpen == 0 && r.ExternalDisplays > 0
+}
+
+// PackReading is the charge of one battery pack at a given sample
+type PackReading struct {
+	Name string
 	Batt float64
 }
 
@@ -98,8 +138,60 @@ func FilterContiguousACState(rows []Row, acState bool) []Row {
 	return filtered
 }
 
+// MobileRows drops samples taken while docked (see Row.Docked)
+func MobileRows(rows []Row) []Row {
+	var out []Row
+	for _, r := range rows {
+		if !r.Docked() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// TrailingMobile returns the samples after the last docked one, i.e. the
+// current mobile session, or nil while docked
+func TrailingMobile(rows []Row) []Row {
+	for i := len(rows) - 1; i >= 0; i-- {
+		if rows[i].Docked() {
+			return rows[i+1:]
+		}
+	}
+	return rows
+}
+
+// ChargeTarget returns the level charging is expected to stop at: the
+// firmware end threshold logged with the latest row when it is below
+// maxChargePercent, otherwise maxChargePercent.
+func ChargeTarget(rows []Row, maxChargePercent int) int {
+	if len(rows) == 0 {
+		return maxChargePercent
+	}
+	if limit := rows[len(rows)-1].ChargeLimit; limit > 0 && limit < maxChargePercent {
+		return limit
+	}
+	return maxChargePercent
+}
+
+// IsHeldAtLimit reports whether the pack is plugged in but deliberately not
+// charging, either because the kernel says "Not charging" or, for logs
+// without a status column, because it sits at a charge target below 100%.
+func IsHeldAtLimit(latest Row, target int) bool {
+	if !latest.AC {
+		return false
+	}
+	switch latest.State {
+	case StateNotCharging:
+		return true
+	case StateCharging, StateDischarging, StateFull:
+		return false
+	}
+	return target < 100 && latest.Batt >= float64(target)
+}
+
 // CalculateRateAndEstimate calculates the battery rate and time estimate based on AC state.
-// For charging (AC=true): returns positive rate and time to maxChargePercent.
+// For charging (AC=true): returns positive rate and time to maxChargePercent
+// (see ChargeTarget), or a zero estimate when the pack is held at its limit.
 // For discharging (AC=false): returns negative rate and time to 0%.
 // Returns rate (% per minute), estimate (minutes), confidence string, and success flag.
 func CalculateRateAndEstimate(rows []Row, currentBatt float64, alpha float64, maxChargePercent int) (float64, float64, string, bool) {
@@ -118,10 +210,15 @@ func CalculateRateAndEstimate(rows []Row, currentBatt float64, alpha float64, ma
 	var estimate float64
 	var confidence string
 
+	if isCharging && IsHeldAtLimit(rows[len(rows)-1], maxChargePercent) {
+		// Firmware is holding the pack; there is nothing left to charge
+		return rate, 0, fmt.Sprintf("(held at limit, target %d%%)", maxChargePercent), true
+	}
+
 	if isCharging {
 		// When charging, rate should be positive (battery % increasing)
 		if rate > 1e-6 { // Positive rate means charging
-			estimate = (float64(maxChargePercent) - currentBatt) / rate // Time to reach max charge
+			estimate = math.Max(0, float64(maxChargePercent)-currentBatt) / rate // Time to reach max charge
 			confidence = fmt.Sprintf("(based on %d charging samples)", len(rows))
 		} else {
 			// Rate is negative or zero while plugged in - not actually charging
@@ -146,8 +243,13 @@ func CalculateRateAndEstimate(rows []Row, currentBatt float64, alpha float64, ma
 // ParseCSVRows parses CSV data with flexible column detection and
 // converts it to a slice of Row structs. The CSV must contain
 // timestamp, AC connection status, and battery percentage columns.
-// Column names are matched case-insensitively with various aliases supported.
+// Column names come from the logfile column registry and are matched
+// case-insensitively, including their aliases. A leading schema marker is
+// skipped.
 func ParseCSVRows(rows [][]string) ([]Row, error) {
+	for len(rows) > 0 && len(rows[0]) > 0 && strings.HasPrefix(rows[0][0], "#") {
+		rows = rows[1:]
+	}
 	if len(rows) == 0 {
 		return nil, errors.New("empty csv")
 	}
@@ -156,6 +258,7 @@ func ParseCSVRows(rows [][]string) ([]Row, error) {
 	if err != nil {
 		return nil, err
 	}
+	opt := findOptionalColumns(rows[0])
 
 	var out []Row
 	for i := 1; i < len(rows); i++ {
@@ -163,41 +266,136 @@ func ParseCSVRows(rows [][]string) ([]Row, error) {
 		if err != nil {
 			continue
 		}
+		opt.apply(&row, rows[i])
 		out = append(out, row)
 	}
 	return out, nil
 }
 
-func findColumns(header []string) (tsIdx, acIdx, battIdx int, err error) {
-	col := func(name string) int {
-		name = strings.ToLower(strings.TrimSpace(name))
-		for i, h := range header {
-			if strings.ToLower(strings.TrimSpace(h)) == name {
-				return i
-			}
-		}
-		return -1
+// optionalColumns holds indices of columns that newer logs carry; -1 if absent.
+type optionalColumns struct {
+	packs, energyNow, energyFull, energyFullDesign, power, status, capacityLevel, event, chargeLimit, charger, chargerType, chargerMaxW, screenOn, idleSecs, cpuUtil, loadAvg, cpuFreq, profile, suspended, bootID, uptime, lidOpen, externalDisplays int
+}
+
+func findOptionalColumns(header []string) optionalColumns {
+	return optionalColumns{
+		packs:            colIndex(header, logfile.ColPacks),
+		energyNow:        colIndex(header, logfile.ColEnergyNow),
+		energyFull:       colIndex(header, logfile.ColEnergyFull),
+		energyFullDesign: colIndex(header, logfile.ColEnergyFullDesign),
+		power:            colIndex(header, logfile.ColPower),
+		status:           colIndex(header, logfile.ColStatus),
+		capacityLevel:    colIndex(header, logfile.ColCapacityLevel),
+		event:            colIndex(header, logfile.ColEvent),
+		chargeLimit:      colIndex(header, logfile.ColChargeLimit),
+		charger:          colIndex(header, logfile.ColCharger),
+		chargerType:      colIndex(header, logfile.ColChargerType),
+		chargerMaxW:      colIndex(header, logfile.ColChargerMaxW),
+		screenOn:         colIndex(header, logfile.ColScreenOn),
+		idleSecs:         colIndex(header, logfile.ColIdleSecs),
+		cpuUtil:          colIndex(header, logfile.ColCPUUtil),
+		loadAvg:          colIndex(header, logfile.ColLoadAvg),
+		cpuFreq:          colIndex(header, logfile.ColCPUFreq),
+		profile:          colIndex(header, logfile.ColProfile),
+		suspended:        colIndex(header, logfile.ColSuspended),
+		bootID:           colIndex(header, logfile.ColBootID),
+		uptime:           colIndex(header, logfile.ColUptime),
+		lidOpen:          colIndex(header, logfile.ColLidOpen),
+		externalDisplays: colIndex(header, logfile.ColExternalDisplays),
 	}
+}
 
-	tsIdx = col("timestamp")
-	acIdx = col("ac_connected")
-	if acIdx == -1 {
-		acIdx = col("ac")
+// apply fills the optional fields of row from rec. Missing or malformed
+// values are left as NaN or empty.
+func (c optionalColumns) apply(row *Row, rec []string) {
+	field := func(idx int) string {
+		if idx < 0 || idx >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[idx])
 	}
-	if acIdx == -1 {
-		acIdx = col("ac plugged in (bool)")
+	float := func(idx int) float64 {
+		v, err := strconv.ParseFloat(field(idx), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return v
+	}
+
+	row.Packs = parsePacks(field(c.packs))
+	row.EnergyNow = float(c.energyNow)
+	row.EnergyFull = float(c.energyFull)
+	row.EnergyFullDesign = float(c.energyFullDesign)
+	row.PowerW = float(c.power)
+	row.Status = field(c.status)
+	row.State = ParseState(row.Status)
+	row.CapacityLevel = field(c.capacityLevel)
+	row.Event = field(c.event)
+	row.Charger = field(c.charger)
+	row.ChargerType = field(c.chargerType)
+	row.ChargerMaxW = float(c.chargerMaxW)
+	row.CPUUtil = float(c.cpuUtil)
+	row.LoadAvg = float(c.loadAvg)
+	row.CPUFreqMHz = float(c.cpuFreq)
+	row.Profile = field(c.profile)
+	row.SuspendedSecs = float(c.suspended)
+	row.BootID = field(c.bootID)
+	row.UptimeSecs = float(c.uptime)
+	row.ChargeLimit = -1
+	if v, err := strconv.Atoi(field(c.chargeLimit)); err == nil {
+		row.ChargeLimit = v
+	}
+	row.IdleSecs = -1
+	if v, err := strconv.Atoi(field(c.idleSecs)); err == nil {
+		row.IdleSecs = v
+	}
+	row.ScreenOn = optionalBool(field(c.screenOn))
+	row.LidOpen = optionalBool(field(c.lidOpen))
+	row.ExternalDisplays = -1
+	if v, err := strconv.Atoi(field(c.externalDisplays)); err == nil {
+		row.ExternalDisplays = v
 	}
-	if acIdx == -1 {
-		acIdx = col("ac plugged in")
+}
+
+// optionalBool parses a logged flag as 1 or 0, or -1 if empty or malformed
+func optionalBool(s string) int {
+	v, err := ParseBoolLoose(s)
+	if err != nil {
+		return -1
 	}
-	battIdx = col("battery_life")
-	if battIdx == -1 {
-		battIdx = col("battery")
+	if v {
+		return 1
 	}
-	if battIdx == -1 {
-		battIdx = col("battery life (%)")
+	return 0
+}
+
+// colIndex returns the index of the named column, or -1. Registered log
+// columns also match their aliases.
+func colIndex(header []string, name string) int {
+	return logfile.ColumnIndex(header, name)
+}
+
+// parsePacks decodes a "BAT0=85;BAT1=60" breakdown, skipping malformed entries.
+func parsePacks(s string) []PackReading {
+	var packs []PackReading
+	for _, part := range strings.Split(strings.TrimSpace(s), ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			continue
+		}
+		packs = append(packs, PackReading{Name: strings.TrimSpace(name), Batt: b})
 	}
+	return packs
+}
 
+func findColumns(header []string) (tsIdx, acIdx, battIdx int, err error) {
+	tsIdx = colIndex(header, logfile.ColTimestamp)
+	acIdx = colIndex(header, logfile.ColAC)
+	battIdx = colIndex(header, logfile.ColBattery)
 	if tsIdx == -1 || acIdx == -1 || battIdx == -1 {
 		return -1, -1, -1, fmt.Errorf("expected headers: timestamp, ac_connected, battery_life (or similar)")
 	}
@@ -209,7 +407,7 @@ func parseCSVRow(rec []string, tsIdx, acIdx, battIdx int) (Row, error) {
 		return Row{}, fmt.Errorf("insufficient columns")
 	}
 
-	t, err := parseTimestamp(strings.TrimSpace(rec[tsIdx]))
+	t, err := logfile.ParseTimestamp(strings.TrimSpace(rec[tsIdx]))
 	if err != nil {
 		return Row{}, err
 	}
@@ -227,37 +425,121 @@ func parseCSVRow(rec []string, tsIdx, acIdx, battIdx int) (Row, error) {
 	return Row{T: t, AC: ac, Batt: b}, nil
 }
 
-func parseTimestamp(tsStr string) (time.Time, error) {
-	t, err := time.Parse(time.RFC3339, tsStr)
-	if err == nil {
-		return t, nil
-	}
+// SuspendKind tells what a SuspendEvent covers
+type SuspendKind string
 
-	layouts := []string{
-		"2006-01-02 15:04:05",
-		"2006-01-02 15:04:05 -0700",
-		"2006-01-02T15:04:05",
-	}
-	for _, lay := range layouts {
-		if tt, e2 := time.Parse(lay, tsStr); e2 == nil {
-			return tt, nil
-		}
-	}
-	return time.Time{}, fmt.Errorf("unable to parse timestamp")
+const (
+	KindSuspend         SuspendKind = "suspend"
+	KindLikelyHibernate SuspendKind = "likely hibernate" // Guessed from the drain, see sleepKind
+	KindShutdown        SuspendKind = "shutdown"         // Power-off or reboot
+	KindDaemonDown      SuspendKind = "daemon-down"      // Awake, but nothing was logged
+)
+
+// Asleep reports whether the system was not running during the event
+func (k SuspendKind) Asleep() bool {
+	return k != KindDaemonDown
 }
 
 // SuspendEvent represents a detected suspend/shutdown period
 type SuspendEvent struct {
+	Kind          SuspendKind
 	StartTime     time.Time
 	EndTime       time.Time
-	Duration      time.Duration
+	Duration      time.Duration // Time asleep or off; may be shorter than EndTime-StartTime when Measured
 	BatteryBefore float64
 	BatteryAfter  float64
 	BatteryDrop   float64
+	Measured      bool // Duration comes from suspended_secs or uptime rather than the logging gap
+}
+
+// minSuspend ignores suspended_secs deltas too small to be a real suspend,
+// i.e. jitter between reading the two clocks
+const minSuspend = time.Second
+
+// A hibernated machine is powered off, so a long sleep on battery within one
+// boot that drained less than hibernateMaxDrain is likely a hibernate rather
+// than suspend to RAM. Nothing in the log records which it was; a very
+// frugal S3 suspend can still drain this little.
+const (
+	hibernateMinDuration = time.Hour
+	hibernateMaxDrain    = 0.1 // %/h
+)
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
 }
 
-// DetectSuspendEvents identifies periods where data logging was interrupted,
-// indicating system suspend or shutdown. Returns events in chronological order.
+// rebooted reports whether two samples come from different boots: the boot
+// IDs differ, or a counter that only grows within a boot went backwards
+func rebooted(prev, cur Row) bool {
+	if prev.BootID != "" && cur.BootID != "" {
+		return prev.BootID != cur.BootID
+	}
+	if !math.IsNaN(prev.SuspendedSecs) && !math.IsNaN(cur.SuspendedSecs) && cur.SuspendedSecs < prev.SuspendedSecs {
+		return true
+	}
+	return !math.IsNaN(prev.UptimeSecs) && !math.IsNaN(cur.UptimeSecs) && cur.UptimeSecs < prev.UptimeSecs
+}
+
+// sleepKind guesses whether a sleep within one boot was a suspend or a
+// hibernate from how much it drained
+func sleepKind(prev, cur Row, slept time.Duration) SuspendKind {
+	if !prev.AC && !cur.AC && slept >= hibernateMinDuration && (prev.Batt-cur.Batt)/slept.Hours() < hibernateMaxDrain {
+		return KindLikelyHibernate
+	}
+	return KindSuspend
+}
+
+// suspendedBetween returns how long the system slept or was off between two
+// samples, what kind of gap that was, and whether the duration was measured.
+// Across a reboot the boot time from uptime_secs bounds the off time. Within
+// a boot, rows that both carry suspended_secs give the kernel's exact
+// figure, so a stalled daemon is not mistaken for suspend and a short
+// suspend is not mistaken for activity. A machine that has not suspended
+// since boot reads 0 on both sides of a power-off too, so 0 → 0 proves
+// nothing and is left to the gap. Otherwise a gap of at least threshold
+// counts as asleep in full.
+func suspendedBetween(prev, cur Row, threshold time.Duration) (time.Duration, SuspendKind, bool) {
+	gap := cur.T.Sub(prev.T)
+	if rebooted(prev, cur) {
+		if math.IsNaN(cur.UptimeSecs) {
+			return gap, KindShutdown, false
+		}
+		off := cur.T.Add(-seconds(cur.UptimeSecs)).Sub(prev.T)
+		return max(0, min(off, gap)), KindShutdown, true
+	}
+	neverSuspended := prev.SuspendedSecs == 0 && cur.SuspendedSecs == 0
+	if !math.IsNaN(prev.SuspendedSecs) && !math.IsNaN(cur.SuspendedSecs) && !neverSuspended {
+		slept := seconds(cur.SuspendedSecs - prev.SuspendedSecs)
+		if slept < minSuspend {
+			return 0, "", true
+		}
+		slept = max(0, min(slept, gap))
+		return slept, sleepKind(prev, cur, slept), true
+	}
+	if gap >= threshold {
+		if neverSuspended {
+			// Within one boot the counter would have risen: a power-off
+			return gap, KindShutdown, false
+		}
+		return gap, sleepKind(prev, cur, gap), false
+	}
+	return 0, "", false
+}
+
+// awakeBetween reports whether the system stayed awake between two samples,
+// so the interval can be used for rates
+func awakeBetween(prev, cur Row, threshold time.Duration) bool {
+	slept, _, _ := suspendedBetween(prev, cur, threshold)
+	return slept == 0
+}
+
+// DetectSuspendEvents identifies periods the system spent suspended,
+// hibernated or shut down, plus daemon-down periods where it was awake for
+// at least gapThresholdMinutes without logging. Intervals between rows
+// with suspended_secs use the exact time asleep; older rows fall back to
+// logging gaps of at least gapThresholdMinutes. Returns events in
+// chronological order.
 func DetectSuspendEvents(rows []Row, gapThresholdMinutes int) []SuspendEvent {
 	if len(rows) < 2 {
 		return nil
@@ -267,34 +549,85 @@ func DetectSuspendEvents(rows []Row, gapThresholdMinutes int) []SuspendEvent {
 	threshold := time.Duration(gapThresholdMinutes) * time.Minute
 
 	for i := 1; i < len(rows); i++ {
-		gap := rows[i].T.Sub(rows[i-1].T)
-		if gap >= threshold {
-			event := SuspendEvent{
-				StartTime:     rows[i-1].T,
-				EndTime:       rows[i].T,
-				Duration:      gap,
-				BatteryBefore: rows[i-1].Batt,
-				BatteryAfter:  rows[i].Batt,
-				BatteryDrop:   rows[i-1].Batt - rows[i].Batt,
-			}
+		prev, cur := rows[i-1], rows[i]
+		slept, kind, measured := suspendedBetween(prev, cur, threshold)
+		event := SuspendEvent{
+			StartTime:     prev.T,
+			EndTime:       cur.T,
+			BatteryBefore: prev.Batt,
+			BatteryAfter:  cur.Batt,
+			BatteryDrop:   prev.Batt - cur.Batt,
+			Measured:      measured,
+		}
+		if slept > 0 {
+			event.Kind, event.Duration = kind, slept
 			events = append(events, event)
 		}
+		// Only a measured sleep within one boot tells how long the
+		// system was awake but not logging
+		if measured && kind != KindShutdown {
+			if awake := cur.T.Sub(prev.T) - slept; awake >= threshold {
+				event.Kind, event.Duration = KindDaemonDown, awake
+				events = append(events, event)
+			}
+		}
 	}
 
 	return events
 }
 
+// LastSleep returns the most recent event where the system was not running,
+// or nil
+func LastSleep(events []SuspendEvent) *SuspendEvent {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Kind.Asleep() {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+// IdleAfter is how long without input before screen-on time stops counting
+// as interactive, like a screen-saver timeout: reading a page without
+// touching the keyboard is still use.
+const IdleAfter = 5 * time.Minute
+
 // ScreenOnTimeResult holds screen-on time calculation results
 type ScreenOnTimeResult struct {
-	TotalActiveTime   time.Duration  // Total time with active data points
-	SuspendTime       time.Duration  // Total time in suspend/shutdown
-	LastActiveSession time.Duration  // Active time since last suspend/wake
+	TotalActiveTime   time.Duration  // Total time with the screen on
+	InteractiveTime   time.Duration  // Screen-on time with recent input
+	IdleTime          time.Duration  // Screen-on time with no input for IdleAfter
+	ScreenOffTime     time.Duration  // Awake with the screen off (logs with screen_on only)
+	DockedTime        time.Duration  // Screen-on time with the lid closed on an external display
+	MobileTime        time.Duration  // Screen-on time excluding DockedTime
+	SuspendTime       time.Duration  // Total time suspended, hibernated or shut down
+	LastActiveSession time.Duration  // Screen-on time since last suspend/wake
 	SuspendEvents     []SuspendEvent // All suspend events in the period
 }
 
-// CalculateScreenOnTime calculates screen-on time by detecting gaps in data logging.
-// Active time = total time span - suspend time (gaps >= threshold).
-// This is a proxy for screen-on time since logging typically happens when system is active.
+// idlePortion returns how much of the interval ending at r was idle, given
+// the seconds since the last input logged with r
+func idlePortion(r Row, interval time.Duration) time.Duration {
+	if r.IdleSecs < 0 {
+		return 0
+	}
+	idle := time.Duration(r.IdleSecs)*time.Second - IdleAfter
+	if idle <= 0 {
+		return 0
+	}
+	if idle > interval {
+		return interval
+	}
+	return idle
+}
+
+// CalculateScreenOnTime calculates screen-on time from the logged screen
+// state. Time asleep (see DetectSuspendEvents) is excluded; the awake part
+// of each interval takes the screen state of the sample that starts it. Rows without a
+// screen state (logs predating screen_on) count as screen-on, so old data
+// falls back to pure gap detection. Screen-on time is further split into
+// interactive and idle time using idle_secs; without it, all of it counts
+// as interactive.
 func CalculateScreenOnTime(rows []Row, gapThresholdMinutes int) ScreenOnTimeResult {
 	result := ScreenOnTimeResult{}
 
@@ -307,22 +640,37 @@ func CalculateScreenOnTime(rows []Row, gapThresholdMinutes int) ScreenOnTimeResu
 
 	// Calculate total suspend time
 	for _, event := range result.SuspendEvents {
-		result.SuspendTime += event.Duration
+		if event.Kind.Asleep() {
+			result.SuspendTime += event.Duration
+		}
 	}
 
-	// Total time span
-	totalTimeSpan := rows[len(rows)-1].T.Sub(rows[0].T)
-
-	// Active time = total span - suspend time
-	result.TotalActiveTime = totalTimeSpan - result.SuspendTime
-
-	// Calculate time since last suspend/wake (current active session)
-	if len(result.SuspendEvents) > 0 {
-		lastSuspendEnd := result.SuspendEvents[len(result.SuspendEvents)-1].EndTime
-		result.LastActiveSession = rows[len(rows)-1].T.Sub(lastSuspendEnd)
-	} else {
-		// No suspends detected, entire period is one session
-		result.LastActiveSession = result.TotalActiveTime
+	threshold := time.Duration(gapThresholdMinutes) * time.Minute
+	for i := 1; i < len(rows); i++ {
+		gap := rows[i].T.Sub(rows[i-1].T)
+		slept, _, _ := suspendedBetween(rows[i-1], rows[i], threshold)
+		if slept > 0 {
+			// Current session restarts after each suspend/wake
+			result.LastActiveSession = 0
+			gap -= slept
+		}
+		if gap <= 0 {
+			continue
+		}
+		if rows[i-1].ScreenOn == 0 {
+			result.ScreenOffTime += gap
+			continue
+		}
+		idle := idlePortion(rows[i], gap)
+		result.TotalActiveTime += gap
+		if rows[i-1].Docked() {
+			result.DockedTime += gap
+		} else {
+			result.MobileTime += gap
+		}
+		result.IdleTime += idle
+		result.InteractiveTime += gap - idle
+		result.LastActiveSession += gap
 	}
 
 	return result
@@ -0,0 +1,79 @@
+package analytics
+
+import (
+	"math"
+	"time"
+)
+
+// BootSession summarises the log rows recorded during one boot
+type BootSession struct {
+	BootID    string
+	Boot      time.Time // From uptime_secs, or the first sample
+	End       time.Time // Last sample
+	Samples   int
+	Asleep    time.Duration // Suspended or hibernated
+	StartBatt float64
+	EndBatt   float64
+	Used      float64 // Percentage points discharged
+	Charged   float64 // Percentage points gained
+	UsedWh    float64 // Energy discharged, NaN without energy readings
+}
+
+// Awake returns the time the system ran during the session
+func (b BootSession) Awake() time.Duration {
+	return max(0, b.End.Sub(b.Boot)-b.Asleep)
+}
+
+// BootSessions groups rows by boot_id, oldest first. Rows without a boot ID
+// (older logs) are skipped.
+func BootSessions(rows []Row, gapThresholdMinutes int) []BootSession {
+	threshold := time.Duration(gapThresholdMinutes) * time.Minute
+	var sessions []BootSession
+	var cur *BootSession
+	for i, r := range rows {
+		if r.BootID == "" {
+			cur = nil
+			continue
+		}
+		if cur == nil || cur.BootID != r.BootID {
+			sessions = append(sessions, newBootSession(r))
+			cur = &sessions[len(sessions)-1]
+			continue
+		}
+		prev := rows[i-1]
+		if slept, kind, _ := suspendedBetween(prev, r, threshold); kind.Asleep() {
+			cur.Asleep += slept
+		}
+		if d := prev.Batt - r.Batt; d > 0 {
+			cur.Used += d
+		} else {
+			cur.Charged -= d
+		}
+		if d := prev.EnergyNow - r.EnergyNow; d > 0 {
+			if math.IsNaN(cur.UsedWh) {
+				cur.UsedWh = 0
+			}
+			cur.UsedWh += d
+		}
+		cur.End = r.T
+		cur.EndBatt = r.Batt
+		cur.Samples++
+	}
+	return sessions
+}
+
+func newBootSession(r Row) BootSession {
+	boot := r.T
+	if !math.IsNaN(r.UptimeSecs) {
+		boot = r.T.Add(-seconds(r.UptimeSecs))
+	}
+	return BootSession{
+		BootID:    r.BootID,
+		Boot:      boot,
+		End:       r.T,
+		Samples:   1,
+		StartBatt: r.Batt,
+		EndBatt:   r.Batt,
+		UsedWh:    math.NaN(),
+	}
+}
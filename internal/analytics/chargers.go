@@ -0,0 +1,173 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// ChargeSession is a contiguous plugged-in period on one charger
+type ChargeSession struct {
+	Charger     string
+	ChargerType string
+	ChargerMaxW float64 // NaN if the supply does not report it
+	Start       time.Time
+	End         time.Time
+	StartBatt   float64
+	EndBatt     float64
+	Charging    time.Duration // Time actually spent charging (not held or full)
+	Rate        float64       // % per minute while charging, NaN if too few samples
+	AvgPowerW   float64       // Mean power into the battery while charging, NaN if unknown
+}
+
+// isCharging reports whether a plugged-in row was actively charging. Rows
+// without a known state count when plugged in below 100%.
+func isCharging(r Row) bool {
+	if !r.AC {
+		return false
+	}
+	if r.State != StateUnknown {
+		return r.State == StateCharging
+	}
+	return r.Batt < 100
+}
+
+// ChargeSessions splits rows into plugged-in sessions tagged with the
+// charger in use. A session ends on unplug, on a charger change, or on a
+// suspend (a logging gap of at least gapThresholdMinutes in older logs). Rows from logs without a
+// charger column are grouped under an empty charger name.
+func ChargeSessions(rows []Row, gapThresholdMinutes int) []ChargeSession {
+	gap := time.Duration(gapThresholdMinutes) * time.Minute
+
+	var sessions []ChargeSession
+	var cur []Row
+	flush := func() {
+		if len(cur) > 0 {
+			sessions = append(sessions, newChargeSession(cur))
+		}
+		cur = nil
+	}
+
+	for _, r := range rows {
+		if !r.AC {
+			flush()
+			continue
+		}
+		if len(cur) > 0 {
+			prev := cur[len(cur)-1]
+			if r.Charger != prev.Charger || !awakeBetween(prev, r, gap) {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return sessions
+}
+
+func newChargeSession(rows []Row) ChargeSession {
+	first, last := rows[0], rows[len(rows)-1]
+	s := ChargeSession{
+		Charger:     last.Charger,
+		ChargerType: last.ChargerType,
+		ChargerMaxW: last.ChargerMaxW,
+		Start:       first.T,
+		End:         last.T,
+		StartBatt:   first.Batt,
+		EndBatt:     last.Batt,
+		Rate:        math.NaN(),
+		AvgPowerW:   math.NaN(),
+	}
+
+	var charging []Row
+	var powerSum float64
+	var powerN int
+	for i, r := range rows {
+		if !isCharging(r) {
+			continue
+		}
+		charging = append(charging, r)
+		if i+1 < len(rows) {
+			s.Charging += rows[i+1].T.Sub(r.T)
+		}
+		if !math.IsNaN(r.PowerW) {
+			powerSum += r.PowerW
+			powerN++
+		}
+	}
+	if powerN > 0 {
+		s.AvgPowerW = powerSum / float64(powerN)
+	}
+	// Equal weights (alpha=0): every charging sample counts the same
+	if rate, _, ok := WeightedLinReg(charging, 0); ok && len(charging) >= 2 {
+		s.Rate = rate
+	}
+	return s
+}
+
+// ChargerStat summarises all charge sessions on one charger
+type ChargerStat struct {
+	Charger     string
+	ChargerType string
+	ChargerMaxW float64
+	Sessions    int
+	Charging    time.Duration
+	AvgRate     float64 // % per minute, weighted by charging time; NaN if unknown
+	AvgPowerW   float64 // W, weighted by charging time; NaN if unknown
+}
+
+// ChargerStats aggregates sessions per charger, slowest average rate first
+// so underpowered chargers top the list.
+func ChargerStats(sessions []ChargeSession) []ChargerStat {
+	type acc struct {
+		stat             ChargerStat
+		rateSum, rateW   float64
+		powerSum, powerW float64
+	}
+	byCharger := make(map[string]*acc)
+	var order []string
+
+	for _, s := range sessions {
+		a, ok := byCharger[s.Charger]
+		if !ok {
+			a = &acc{stat: ChargerStat{Charger: s.Charger, ChargerType: s.ChargerType, ChargerMaxW: s.ChargerMaxW}}
+			byCharger[s.Charger] = a
+			order = append(order, s.Charger)
+		}
+		a.stat.Sessions++
+		a.stat.Charging += s.Charging
+		if !math.IsNaN(s.ChargerMaxW) {
+			a.stat.ChargerMaxW = s.ChargerMaxW
+		}
+		w := s.Charging.Minutes()
+		if !math.IsNaN(s.Rate) && w > 0 {
+			a.rateSum += s.Rate * w
+			a.rateW += w
+		}
+		if !math.IsNaN(s.AvgPowerW) && w > 0 {
+			a.powerSum += s.AvgPowerW * w
+			a.powerW += w
+		}
+	}
+
+	stats := make([]ChargerStat, 0, len(order))
+	for _, name := range order {
+		a := byCharger[name]
+		a.stat.AvgRate, a.stat.AvgPowerW = math.NaN(), math.NaN()
+		if a.rateW > 0 {
+			a.stat.AvgRate = a.rateSum / a.rateW
+		}
+		if a.powerW > 0 {
+			a.stat.AvgPowerW = a.powerSum / a.powerW
+		}
+		stats = append(stats, a.stat)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		ri, rj := stats[i].AvgRate, stats[j].AvgRate
+		if math.IsNaN(ri) || math.IsNaN(rj) {
+			return !math.IsNaN(ri) && math.IsNaN(rj)
+		}
+		return ri < rj
+	})
+	return stats
+}
@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChargeSessions(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	row := func(min int, batt float64, charger string, maxW, power float64) Row {
+		r := Row{T: t0.Add(time.Duration(min) * time.Minute), AC: charger != "-", Batt: batt,
+			Charger: charger, ChargerMaxW: maxW, PowerW: power}
+		if !r.AC {
+			r.Charger = ""
+		}
+		return r
+	}
+	var rows []Row
+	for m := 0; m < 3; m++ {
+		rows = append(rows, row(m, 52-float64(m), "-", math.NaN(), 8))
+	}
+	// Barrel charger, 1 %/min
+	for m := 3; m <= 8; m++ {
+		rows = append(rows, row(m, 50+float64(m-3), "ADP1", 65, 30))
+	}
+	rows = append(rows, row(9, 55, "-", math.NaN(), 8)) // Unplugged
+	// USB-C charger, 0.5 %/min
+	for m := 10; m <= 13; m++ {
+		rows = append(rows, row(m, 54+0.5*float64(m-10), "ucsi-source-psy-USBC000:001", 15, 10))
+	}
+	// Swapped straight back to the barrel charger
+	for m := 14; m <= 16; m++ {
+		rows = append(rows, row(m, 56+float64(m-14), "ADP1", 65, 30))
+	}
+	// Suspended for 24 minutes still plugged in, then held at the limit
+	rows = append(rows, row(40, 60, "ADP1", 65, 30), row(41, 61, "ADP1", 65, 30), row(42, 61, "ADP1", 65, 0))
+	rows[len(rows)-1].State = StateNotCharging
+	// A log without charger columns
+	rows = append(rows, row(50, 61, "-", math.NaN(), math.NaN()), row(51, 62, "", math.NaN(), math.NaN()))
+
+	sessions := ChargeSessions(rows, 5)
+	want := []struct {
+		charger        string
+		start, end     int
+		charging       time.Duration
+		rate, avgPower float64
+	}{
+		{"ADP1", 3, 8, 5 * time.Minute, 1, 30},
+		{"ucsi-source-psy-USBC000:001", 10, 13, 3 * time.Minute, 0.5, 10},
+		{"ADP1", 14, 16, 2 * time.Minute, 1, 30},
+		{"ADP1", 40, 42, 2 * time.Minute, 1, 30},
+		{"", 51, 51, 0, math.NaN(), math.NaN()},
+	}
+	if len(sessions) != len(want) {
+		t.Fatalf("ChargeSessions() = %d sessions: %+v", len(sessions), sessions)
+	}
+	for i, w := range want {
+		s := sessions[i]
+		if s.Charger != w.charger || !s.Start.Equal(t0.Add(time.Duration(w.start)*time.Minute)) ||
+			!s.End.Equal(t0.Add(time.Duration(w.end)*time.Minute)) || s.Charging != w.charging ||
+			!approx(s.Rate, w.rate) || !approx(s.AvgPowerW, w.avgPower) {
+			t.Errorf("session %d = %+v", i, s)
+		}
+	}
+	if s := sessions[0]; s.StartBatt != 50 || s.EndBatt != 55 || s.ChargerMaxW != 65 {
+		t.Errorf("first session = %+v", s)
+	}
+
+	stats := ChargerStats(sessions)
+	wantStats := []struct {
+		charger  string
+		sessions int
+		charging time.Duration
+		rate     float64
+		power    float64
+		maxW     float64
+	}{
+		// Slowest first; the charger with no rate last
+		{"ucsi-source-psy-USBC000:001", 1, 3 * time.Minute, 0.5, 10, 15},
+		{"ADP1", 3, 9 * time.Minute, 1, 30, 65},
+		{"", 1, 0, math.NaN(), math.NaN(), math.NaN()},
+	}
+	if len(stats) != len(wantStats) {
+		t.Fatalf("ChargerStats() = %+v", stats)
+	}
+	for i, w := range wantStats {
+		s := stats[i]
+		if s.Charger != w.charger || s.Sessions != w.sessions || s.Charging != w.charging ||
+			!approx(s.AvgRate, w.rate) || !approx(s.AvgPowerW, w.power) || !approx(s.ChargerMaxW, w.maxW) {
+			t.Errorf("stat %d = %+v", i, s)
+		}
+	}
+}
+
+func TestChargerStatsWeighting(t *testing.T) {
+	sessions := []ChargeSession{
+		{Charger: "ADP1", ChargerMaxW: math.NaN(), Charging: 10 * time.Minute, Rate: 1, AvgPowerW: 40},
+		{Charger: "ADP1", ChargerMaxW: 65, Charging: 30 * time.Minute, Rate: 0.5, AvgPowerW: 20},
+		// Nothing charged: no weight
+		{Charger: "ADP1", ChargerMaxW: math.NaN(), Rate: math.NaN(), AvgPowerW: math.NaN()},
+	}
+	stats := ChargerStats(sessions)
+	if len(stats) != 1 {
+		t.Fatalf("ChargerStats() = %+v", stats)
+	}
+	s := stats[0]
+	if s.Sessions != 3 || s.Charging != 40*time.Minute || !approx(s.AvgRate, 0.625) || !approx(s.AvgPowerW, 25) || s.ChargerMaxW != 65 {
+		t.Errorf("ChargerStats() = %+v", s)
+	}
+}
+
+func approx(got, want float64) bool {
+	if math.IsNaN(want) {
+		return math.IsNaN(got)
+	}
+	return math.Abs(got-want) < 1e-9
+}
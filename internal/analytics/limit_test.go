@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChargeTarget(t *testing.T) {
+	cases := []struct {
+		name string
+		rows []Row
+		max  int
+		want int
+	}{
+		{"no rows", nil, 100, 100},
+		{"no threshold", []Row{{ChargeLimit: -1}}, 100, 100},
+		{"threshold below max", []Row{{ChargeLimit: 80}}, 100, 80},
+		{"threshold above max", []Row{{ChargeLimit: 90}}, 85, 85},
+		{"threshold at max", []Row{{ChargeLimit: 85}}, 85, 85},
+		{"latest row decides", []Row{{ChargeLimit: 60}, {ChargeLimit: -1}}, 100, 100},
+	}
+	for _, c := range cases {
+		if got := ChargeTarget(c.rows, c.max); got != c.want {
+			t.Errorf("%s: ChargeTarget() = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestIsHeldAtLimit(t *testing.T) {
+	cases := []struct {
+		name   string
+		row    Row
+		target int
+		want   bool
+	}{
+		// Logs without a status column: the level decides
+		{"just below", Row{AC: true, Batt: 79}, 80, false},
+		{"at the limit", Row{AC: true, Batt: 80}, 80, true},
+		{"above the limit", Row{AC: true, Batt: 81}, 80, true},
+		{"no limit", Row{AC: true, Batt: 100}, 100, false},
+		{"unplugged at the limit", Row{Batt: 80}, 80, false},
+		// The kernel's status wins over the level
+		{"not charging below", Row{AC: true, Batt: 60, State: StateNotCharging}, 80, true},
+		{"still charging at", Row{AC: true, Batt: 80, State: StateCharging}, 80, false},
+		{"full above", Row{AC: true, Batt: 81, State: StateFull}, 80, false},
+		{"discharging on AC", Row{AC: true, Batt: 85, State: StateDischarging}, 80, false},
+		{"unplugged, not charging", Row{Batt: 80, State: StateNotCharging}, 80, false},
+	}
+	for _, c := range cases {
+		if got := IsHeldAtLimit(c.row, c.target); got != c.want {
+			t.Errorf("%s: IsHeldAtLimit() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEstimateHeldAtLimit(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	rows := []Row{
+		{T: t0, AC: true, Batt: 79},
+		{T: t0.Add(time.Minute), AC: true, Batt: 80},
+		{T: t0.Add(2 * time.Minute), AC: true, Batt: 80},
+	}
+	if _, est, _, ok := CalculateRateAndEstimate(rows, 80, 0.1, 80); !ok || est != 0 {
+		t.Errorf("estimate at the limit = %v, %v", est, ok)
+	}
+	rows[1].Batt = 79.5
+	if _, est, _, ok := CalculateRateAndEstimate(rows[:2], 79.5, 0.1, 80); !ok || est <= 0 {
+		t.Errorf("estimate just below the limit = %v, %v", est, ok)
+	}
+}
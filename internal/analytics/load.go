@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"math"
+	"time"
+)
+
+// Drain units used by LoadCorrelation
+const (
+	DrainWatts       = "W"
+	DrainPercentHour = "%/h"
+)
+
+// loadBucketEdges split CPU utilisation into the ranges reported by ExplainDrain
+var loadBucketEdges = []float64{0, 10, 25, 50, 75, 100}
+
+// LoadBucket is the average drain over intervals within one CPU load range
+type LoadBucket struct {
+	Lo, Hi      float64 // CPU utilisation range in %
+	Samples     int
+	MeanDrain   float64 // In LoadCorrelation.Unit, NaN if empty
+	MeanFreqMHz float64 // NaN if empty or unknown
+}
+
+// LoadCorrelation explains discharge as baseline + PerPercent × CPU load,
+// fitted by least squares over discharging intervals.
+type LoadCorrelation struct {
+	Unit       string // DrainWatts or DrainPercentHour
+	Samples    int
+	Baseline   float64 // Drain extrapolated to an idle CPU: the hardware floor
+	PerPercent float64 // Extra drain per percentage point of CPU load
+	R2         float64 // Share of drain variance explained by CPU load
+	MeanUtil   float64
+	MeanDrain  float64
+	Buckets    []LoadBucket
+}
+
+// Workload returns the part of the mean drain attributed to CPU load
+func (c LoadCorrelation) Workload() float64 {
+	return c.PerPercent * c.MeanUtil
+}
+
+// WorkloadShare returns Workload as a fraction of MeanDrain
+func (c LoadCorrelation) WorkloadShare() float64 {
+	if !(c.MeanDrain > 0) {
+		return math.NaN()
+	}
+	return math.Max(0, math.Min(1, c.Workload()/c.MeanDrain))
+}
+
+type loadPoint struct {
+	util, drain, freq float64
+}
+
+// loadPoints pairs each discharging interval's CPU load with its drain. Power
+// draw is used when the log has it, otherwise the percentage drop per hour.
+func loadPoints(rows []Row, gapThresholdMinutes int) ([]loadPoint, string) {
+	gap := time.Duration(gapThresholdMinutes) * time.Minute
+	var watts, pct []loadPoint
+	for i := 1; i < len(rows); i++ {
+		prev, cur := rows[i-1], rows[i]
+		dt := cur.T.Sub(prev.T)
+		if prev.AC || cur.AC || dt <= 0 || !awakeBetween(prev, cur, gap) || math.IsNaN(cur.CPUUtil) {
+			continue
+		}
+		if !math.IsNaN(cur.PowerW) {
+			watts = append(watts, loadPoint{cur.CPUUtil, cur.PowerW, cur.CPUFreqMHz})
+		}
+		pct = append(pct, loadPoint{cur.CPUUtil, (prev.Batt - cur.Batt) / dt.Hours(), cur.CPUFreqMHz})
+	}
+	if len(watts) >= 2 {
+		return watts, DrainWatts
+	}
+	return pct, DrainPercentHour
+}
+
+// ExplainDrain correlates discharge with CPU load over rows. ok is false
+// when fewer than two discharging intervals carry a CPU load.
+func ExplainDrain(rows []Row, gapThresholdMinutes int) (LoadCorrelation, bool) {
+	pts, unit := loadPoints(rows, gapThresholdMinutes)
+	c := LoadCorrelation{Unit: unit, Samples: len(pts), Baseline: math.NaN(), PerPercent: math.NaN(), R2: math.NaN()}
+	if len(pts) < 2 {
+		return c, false
+	}
+
+	var sumX, sumY, sumXX, sumXY float64
+	for _, p := range pts {
+		sumX += p.util
+		sumY += p.drain
+		sumXX += p.util * p.util
+		sumXY += p.util * p.drain
+	}
+	n := float64(len(pts))
+	c.MeanUtil = sumX / n
+	c.MeanDrain = sumY / n
+	c.Buckets = loadBuckets(pts)
+
+	den := n*sumXX - sumX*sumX
+	if den == 0 {
+		// Constant load: nothing to correlate against
+		return c, false
+	}
+	c.PerPercent = (n*sumXY - sumX*sumY) / den
+	c.Baseline = (sumY - c.PerPercent*sumX) / n
+
+	var ssRes, ssTot float64
+	for _, p := range pts {
+		fit := c.Baseline + c.PerPercent*p.util
+		ssRes += (p.drain - fit) * (p.drain - fit)
+		ssTot += (p.drain - c.MeanDrain) * (p.drain - c.MeanDrain)
+	}
+	if ssTot > 0 {
+		c.R2 = 1 - ssRes/ssTot
+	}
+	return c, true
+}
+
+func loadBuckets(pts []loadPoint) []LoadBucket {
+	buckets := make([]LoadBucket, len(loadBucketEdges)-1)
+	drainSum := make([]float64, len(buckets))
+	freqSum := make([]float64, len(buckets))
+	freqN := make([]int, len(buckets))
+	for i := range buckets {
+		buckets[i] = LoadBucket{Lo: loadBucketEdges[i], Hi: loadBucketEdges[i+1], MeanDrain: math.NaN(), MeanFreqMHz: math.NaN()}
+	}
+	for _, p := range pts {
+		i := len(buckets) - 1
+		for j, b := range buckets {
+			if p.util < b.Hi {
+				i = j
+				break
+			}
+		}
+		buckets[i].Samples++
+		drainSum[i] += p.drain
+		if !math.IsNaN(p.freq) {
+			freqSum[i] += p.freq
+			freqN[i]++
+		}
+	}
+	for i := range buckets {
+		if buckets[i].Samples > 0 {
+			buckets[i].MeanDrain = drainSum[i] / float64(buckets[i].Samples)
+		}
+		if freqN[i] > 0 {
+			buckets[i].MeanFreqMHz = freqSum[i] / float64(freqN[i])
+		}
+	}
+	return buckets
+}
+
+// RowsSince returns the rows at or after t
+func RowsSince(rows []Row, t time.Time) []Row {
+	for i, r := range rows {
+		if !r.T.Before(t) {
+			return rows[i:]
+		}
+	}
+	return nil
+}
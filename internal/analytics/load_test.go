@@ -0,0 +1,48 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExplainDrain(t *testing.T) {
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	row := func(min int, ac bool, util, power float64) Row {
+		return Row{T: t0.Add(time.Duration(min) * time.Minute), AC: ac, Batt: 80, CPUUtil: util, PowerW: power, CPUFreqMHz: math.NaN()}
+	}
+	// Drain is exactly 4 W + 0.1 W per % of CPU
+	rows := []Row{
+		row(0, false, math.NaN(), 5),
+		row(1, false, 10, 5),
+		row(2, false, 50, 9),
+		row(3, false, 90, 13),
+		row(4, true, 90, 20),  // Plugged in: ignored
+		row(30, false, 0, 4),  // After a suspend gap: ignored
+		row(31, false, 30, 7), // Resumes with a valid interval
+	}
+	c, ok := ExplainDrain(rows, 5)
+	if !ok {
+		t.Fatalf("ExplainDrain() = %+v", c)
+	}
+	if c.Unit != DrainWatts || c.Samples != 4 {
+		t.Errorf("Unit, Samples = %s, %d; want W, 4", c.Unit, c.Samples)
+	}
+	if math.Abs(c.Baseline-4) > 1e-9 || math.Abs(c.PerPercent-0.1) > 1e-9 || math.Abs(c.R2-1) > 1e-9 {
+		t.Errorf("fit = %.3f + %.3f×load (R² %.3f), want 4 + 0.1×load (R² 1)", c.Baseline, c.PerPercent, c.R2)
+	}
+	if b := c.Buckets[1]; b.Samples != 1 || b.MeanDrain != 5 {
+		t.Errorf("10-25%% bucket = %+v", b)
+	}
+}
+
+func TestExplainDrainWithoutLoad(t *testing.T) {
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := []Row{
+		{T: t0, Batt: 80, CPUUtil: math.NaN(), PowerW: math.NaN()},
+		{T: t0.Add(time.Minute), Batt: 79, CPUUtil: math.NaN(), PowerW: math.NaN()},
+	}
+	if c, ok := ExplainDrain(rows, 5); ok || c.Samples != 0 {
+		t.Errorf("ExplainDrain() on a log without cpu_util_pct = %+v, %t", c, ok)
+	}
+}
@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// Suspends of at least NightMinHours count as nights. Shorter ones are too
+// coarse at 1% battery resolution to compare drain rates.
+const NightMinHours = 2.0
+
+// minMedianDrain keeps a near-zero median from flagging every night that
+// lost a single percent
+const minMedianDrain = 0.1 // %/h
+
+// SuspendDrain is the battery drain rate of one suspend or hibernate
+type SuspendDrain struct {
+	SuspendEvent
+	RatePerHour float64 // % per hour asleep; negative if it charged
+	Night       bool    // Long enough to compare against the median
+	Ratio       float64 // RatePerHour over the median night, NaN if not a night
+	Abnormal    bool    // A night draining faster than factor × the median
+}
+
+// SuspendDrains rates every suspend and hibernate in events and flags nights
+// whose drain exceeds factor times the median night. It returns the drains
+// in event order and the median night rate (NaN without nights).
+func SuspendDrains(events []SuspendEvent, factor float64) ([]SuspendDrain, float64) {
+	var out []SuspendDrain
+	var nightRates []float64
+	for _, e := range events {
+		if e.Kind != KindSuspend && e.Kind != KindLikelyHibernate || e.Duration <= 0 {
+			continue
+		}
+		d := SuspendDrain{
+			SuspendEvent: e,
+			RatePerHour:  e.BatteryDrop / e.Duration.Hours(),
+			Ratio:        math.NaN(),
+		}
+		// Nights that charged were plugged in; they say nothing about drain
+		d.Night = e.Duration.Hours() >= NightMinHours && d.RatePerHour >= 0
+		if d.Night {
+			nightRates = append(nightRates, d.RatePerHour)
+		}
+		out = append(out, d)
+	}
+
+	median := math.NaN()
+	if len(nightRates) > 0 {
+		sort.Float64s(nightRates)
+		n := len(nightRates)
+		median = nightRates[n/2]
+		if n%2 == 0 {
+			median = (nightRates[n/2-1] + nightRates[n/2]) / 2
+		}
+	}
+	for i := range out {
+		if !out[i].Night {
+			continue
+		}
+		base := math.Max(median, minMedianDrain)
+		out[i].Ratio = out[i].RatePerHour / base
+		out[i].Abnormal = out[i].RatePerHour > factor*base
+	}
+	return out, median
+}
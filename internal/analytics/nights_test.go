@@ -0,0 +1,37 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSuspendDrains(t *testing.T) {
+	night := func(hours, drop float64) SuspendEvent {
+		return SuspendEvent{Kind: KindSuspend, Duration: time.Duration(hours * float64(time.Hour)), BatteryDrop: drop}
+	}
+	events := []SuspendEvent{
+		night(8, 4),  // 0.5 %/h
+		night(8, 6),  // 0.75 %/h
+		night(8, 24), // 3 %/h: abnormal
+		night(1, 5),  // Too short to be a night
+		night(6, -20),
+		{Kind: KindShutdown, Duration: 8 * time.Hour, BatteryDrop: 1},
+		{Kind: KindDaemonDown, Duration: time.Hour},
+	}
+	got, median := SuspendDrains(events, 2)
+	if len(got) != 5 {
+		t.Fatalf("got %d drains", len(got))
+	}
+	if median != 0.75 {
+		t.Errorf("median = %v, want 0.75", median)
+	}
+	for i, want := range []bool{false, false, true, false, false} {
+		if got[i].Abnormal != want {
+			t.Errorf("drain %d abnormal = %t (rate %v)", i, got[i].Abnormal, got[i].RatePerHour)
+		}
+	}
+	if got[2].Ratio != 4 || !math.IsNaN(got[3].Ratio) || got[4].Night {
+		t.Errorf("drains = %+v", got)
+	}
+}
@@ -0,0 +1,84 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// ProfileDrain is the average discharge while one platform profile was active
+type ProfileDrain struct {
+	Profile   string
+	Samples   int
+	Duration  time.Duration // Discharging time covered by the samples
+	MeanDrain float64       // Time-weighted, in the unit returned by DrainByProfile
+	MeanUtil  float64       // Mean CPU load, NaN if not logged
+}
+
+type profileAcc struct {
+	ProfileDrain
+	drain, hours, util float64
+	utilN              int
+}
+
+// DrainByProfile averages discharge per platform profile, attributing each
+// interval to the profile active at its start. Power draw is used when
+// logged, otherwise the percentage drop per hour. Profiles are ordered by
+// discharging time, longest first.
+func DrainByProfile(rows []Row, gapThresholdMinutes int) ([]ProfileDrain, string) {
+	gap := time.Duration(gapThresholdMinutes) * time.Minute
+	unit := DrainPercentHour
+	for _, r := range rows {
+		if !r.AC && r.Profile != "" && !math.IsNaN(r.PowerW) {
+			unit = DrainWatts
+			break
+		}
+	}
+
+	accs := map[string]*profileAcc{}
+	for i := 1; i < len(rows); i++ {
+		prev, cur := rows[i-1], rows[i]
+		dt := cur.T.Sub(prev.T)
+		if prev.AC || cur.AC || prev.Profile == "" || dt <= 0 || !awakeBetween(prev, cur, gap) {
+			continue
+		}
+		drain := (prev.Batt - cur.Batt) / dt.Hours()
+		if unit == DrainWatts {
+			if math.IsNaN(cur.PowerW) {
+				continue
+			}
+			drain = cur.PowerW
+		}
+		a := accs[prev.Profile]
+		if a == nil {
+			a = &profileAcc{ProfileDrain: ProfileDrain{Profile: prev.Profile}}
+			accs[prev.Profile] = a
+		}
+		a.Samples++
+		a.Duration += dt
+		a.drain += drain * dt.Hours()
+		a.hours += dt.Hours()
+		if !math.IsNaN(cur.CPUUtil) {
+			a.util += cur.CPUUtil
+			a.utilN++
+		}
+	}
+
+	out := make([]ProfileDrain, 0, len(accs))
+	for _, a := range accs {
+		d := a.ProfileDrain
+		d.MeanDrain = a.drain / a.hours
+		d.MeanUtil = math.NaN()
+		if a.utilN > 0 {
+			d.MeanUtil = a.util / float64(a.utilN)
+		}
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Duration != out[j].Duration {
+			return out[i].Duration > out[j].Duration
+		}
+		return out[i].Profile < out[j].Profile
+	})
+	return out, unit
+}
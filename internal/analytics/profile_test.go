@@ -0,0 +1,36 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDrainByProfile(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	row := func(min int, profile string, ac bool, power float64) Row {
+		return Row{T: t0.Add(time.Duration(min) * time.Minute), AC: ac, Profile: profile, PowerW: power, CPUUtil: math.NaN()}
+	}
+	rows := []Row{
+		row(0, "balanced", false, 10),
+		row(1, "balanced", false, 10),
+		row(2, "balanced", false, 12),
+		row(3, "low-power", false, 6),
+		row(4, "low-power", false, 6),
+		row(5, "low-power", true, 6), // Plugged in: ignored
+		row(6, "", false, 8),
+	}
+	got, unit := DrainByProfile(rows, 5)
+	if unit != DrainWatts {
+		t.Fatalf("unit = %q", unit)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d profiles: %+v", len(got), got)
+	}
+	if got[0].Profile != "balanced" || got[0].Samples != 3 || math.Abs(got[0].MeanDrain-28.0/3) > 1e-9 {
+		t.Errorf("balanced = %+v", got[0])
+	}
+	if got[1].Profile != "low-power" || got[1].Samples != 1 || got[1].MeanDrain != 6 {
+		t.Errorf("low-power = %+v", got[1])
+	}
+}
@@ -0,0 +1,248 @@
+package analytics
+
+import (
+	"math"
+	"time"
+)
+
+// RegressionAlpha is the significance level at which a rise in drain after
+// a version change is flagged
+const RegressionAlpha = 0.05
+
+// regressionMinSuspend skips suspends, and regressionMinSession discharge
+// sessions, too short to rate at 1% resolution
+const (
+	regressionMinSuspend = 30 * time.Minute
+	regressionMinSession = 30 * time.Minute
+)
+
+// RegressionMinSamples is the number of sessions or suspends each side of a
+// change needs before the two are tested
+const RegressionMinSamples = 3
+
+// DrainStats summarises drain samples on one side of a version change
+type DrainStats struct {
+	N      int
+	Mean   float64 // NaN without samples
+	StdDev float64 // NaN with fewer than two samples
+}
+
+func drainStats(xs []float64) DrainStats {
+	s := DrainStats{N: len(xs), Mean: math.NaN(), StdDev: math.NaN()}
+	if len(xs) == 0 {
+		return s
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	s.Mean = sum / float64(len(xs))
+	if len(xs) < 2 {
+		return s
+	}
+	var ss float64
+	for _, x := range xs {
+		ss += (x - s.Mean) * (x - s.Mean)
+	}
+	s.StdDev = math.Sqrt(ss / float64(len(xs)-1))
+	return s
+}
+
+// DrainComparison compares one kind of drain before and after a change
+type DrainComparison struct {
+	Unit       string // DrainWatts or DrainPercentHour
+	Before     DrainStats
+	After      DrainStats
+	PValue     float64 // Two-sided Welch's t-test, NaN if either side has under RegressionMinSamples
+	Regression bool    // Drain rose and the rise is significant at RegressionAlpha
+}
+
+// Change returns the relative change of the mean, e.g. 0.25 for 25% more
+func (c DrainComparison) Change() float64 {
+	if !(c.Before.Mean > 0) {
+		return math.NaN()
+	}
+	return c.After.Mean/c.Before.Mean - 1
+}
+
+func compareDrain(unit string, before, after []float64) DrainComparison {
+	c := DrainComparison{Unit: unit, Before: drainStats(before), After: drainStats(after), PValue: math.NaN()}
+	if c.Before.N >= RegressionMinSamples && c.After.N >= RegressionMinSamples {
+		c.PValue = welchPValue(c.Before, c.After)
+	}
+	c.Regression = c.PValue < RegressionAlpha && c.After.Mean > c.Before.Mean
+	return c
+}
+
+// VersionDrain compares awake discharge and suspend drain on either side
+// of a version change
+type VersionDrain struct {
+	Discharge DrainComparison
+	Suspend   DrainComparison // Always in DrainPercentHour
+}
+
+// Regression reports whether either drain regressed
+func (v VersionDrain) Regression() bool {
+	return v.Discharge.Regression || v.Suspend.Regression
+}
+
+// CompareDrainAt compares drain in rows before and after the change at t.
+// Each discharge session (an unbroken awake stretch on battery) of at least
+// 30 minutes is one discharge sample, its mean power in watts when both
+// sides log power and its %/h otherwise; each suspend or hibernate of at
+// least 30 minutes on battery is one suspend sample. Minute intervals are
+// not samples of their own: neighbours are correlated and a 1% step is most
+// of a minute's rate, so testing them flags almost any change. Callers
+// limit rows to the window around t, ideally stopping at neighbouring
+// changes.
+func CompareDrainAt(rows []Row, t time.Time, gapThresholdMinutes int) VersionDrain {
+	before, after := rows, []Row(nil)
+	for i, r := range rows {
+		if !r.T.Before(t) {
+			before, after = rows[:i], rows[i:]
+			break
+		}
+	}
+
+	bw, bp := sessionRates(before, gapThresholdMinutes)
+	aw, ap := sessionRates(after, gapThresholdMinutes)
+	var v VersionDrain
+	if len(bw) >= RegressionMinSamples && len(aw) >= RegressionMinSamples {
+		v.Discharge = compareDrain(DrainWatts, bw, aw)
+	} else {
+		v.Discharge = compareDrain(DrainPercentHour, bp, ap)
+	}
+	v.Suspend = compareDrain(DrainPercentHour,
+		suspendRates(before, gapThresholdMinutes), suspendRates(after, gapThresholdMinutes))
+	return v
+}
+
+// sessionRates returns the mean power draw and the percentage drop per
+// hour of every discharge session long enough to rate. A session ends at
+// AC, a suspend or a reboot. Sessions without power readings only get a
+// %/h rate.
+func sessionRates(rows []Row, gapThresholdMinutes int) (watts, pct []float64) {
+	gap := time.Duration(gapThresholdMinutes) * time.Minute
+	start := -1 // First row of the current session
+	var power float64
+	var powered int
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			first, last := rows[start], rows[end]
+			if d := last.T.Sub(first.T); d >= regressionMinSession {
+				pct = append(pct, (first.Batt-last.Batt)/d.Hours())
+				if powered > 0 {
+					watts = append(watts, power/float64(powered))
+				}
+			}
+		}
+		start, power, powered = -1, 0, 0
+	}
+	for i := 1; i < len(rows); i++ {
+		prev, cur := rows[i-1], rows[i]
+		if prev.AC || cur.AC || !cur.T.After(prev.T) || !awakeBetween(prev, cur, gap) {
+			flush(i - 1)
+			continue
+		}
+		if start < 0 {
+			start = i - 1
+		}
+		if !math.IsNaN(cur.PowerW) {
+			power += cur.PowerW
+			powered++
+		}
+	}
+	flush(len(rows) - 1)
+	return watts, pct
+}
+
+// suspendRates returns the drain of each suspend long enough to rate.
+// Suspends that charged were plugged in and are left out.
+func suspendRates(rows []Row, gapThresholdMinutes int) []float64 {
+	var out []float64
+	for _, e := range DetectSuspendEvents(rows, gapThresholdMinutes) {
+		if e.Kind != KindSuspend && e.Kind != KindLikelyHibernate || e.Duration < regressionMinSuspend {
+			continue
+		}
+		if rate := e.BatteryDrop / e.Duration.Hours(); rate >= 0 {
+			out = append(out, rate)
+		}
+	}
+	return out
+}
+
+// welchPValue is the two-sided p-value of Welch's t-test for a difference
+// in means
+func welchPValue(a, b DrainStats) float64 {
+	if a.N < 2 || b.N < 2 {
+		return math.NaN()
+	}
+	va := a.StdDev * a.StdDev / float64(a.N)
+	vb := b.StdDev * b.StdDev / float64(b.N)
+	se := va + vb
+	if se == 0 {
+		if a.Mean == b.Mean {
+			return 1
+		}
+		return 0
+	}
+	t := (b.Mean - a.Mean) / math.Sqrt(se)
+	df := se * se / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	// P(|T| > t) for Student's t with df degrees of freedom
+	return incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated by continued fraction (Numerical Recipes, betai)
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+func betaFraction(a, b, x float64) float64 {
+	const (
+		maxIter = 200
+		eps     = 1e-12
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < eps {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWelchPValue(t *testing.T) {
+	// Equal sizes and variances: t = 2 with 10 degrees of freedom
+	a := DrainStats{N: 6, Mean: 0, StdDev: math.Sqrt(3)}
+	b := DrainStats{N: 6, Mean: 2, StdDev: math.Sqrt(3)}
+	if p := welchPValue(a, b); math.Abs(p-0.07339) > 1e-4 {
+		t.Errorf("p = %v, want 0.0734", p)
+	}
+	if p := welchPValue(a, a); math.Abs(p-1) > 1e-9 {
+		t.Errorf("p for equal means = %v", p)
+	}
+	if p := welchPValue(DrainStats{N: 1}, b); !math.IsNaN(p) {
+		t.Errorf("p with one sample = %v", p)
+	}
+}
+
+func TestCompareDrainAt(t *testing.T) {
+	t0 := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	var rows []Row
+	batt := 100.0
+	suspended := 0.0
+	add := func(at time.Time, power float64) {
+		rows = append(rows, Row{T: at, Batt: batt, PowerW: power, SuspendedSecs: suspended, UptimeSecs: math.NaN(), CPUUtil: math.NaN()})
+	}
+	// Three days before the upgrade at 8 W with 0.5 %/h nights, three
+	// after at 11 W with 2 %/h nights
+	upgrade := t0.Add(72 * time.Hour)
+	for day := 0; day < 6; day++ {
+		power, night := 8.0, 0.5
+		if day >= 3 {
+			power, night = 11, 2
+		}
+		power += 0.2 * float64(day%3) // Sessions differ a little
+		night += 0.1 * float64(day%3)
+		start := t0.Add(time.Duration(day) * 24 * time.Hour)
+		for m := 0; m < 60; m++ {
+			add(start.Add(time.Duration(m)*time.Minute), power+float64(m%3)-1)
+			batt -= 0.1
+		}
+		suspended += 8 * 3600
+		batt -= 8 * night
+		add(start.Add(60*time.Minute+8*time.Hour), power)
+		batt = 100
+		add(start.Add(23*time.Hour), math.NaN())
+		rows[len(rows)-1].AC = true // Recharged during the day
+	}
+
+	got := CompareDrainAt(rows, upgrade, 5)
+	d := got.Discharge
+	if d.Unit != DrainWatts || !d.Regression || math.Abs(d.Before.Mean-8.2) > 0.1 || math.Abs(d.After.Mean-11.2) > 0.1 {
+		t.Errorf("discharge = %+v", d)
+	}
+	s := got.Suspend
+	if s.Before.N != 3 || s.After.N != 3 || !s.Regression || math.Abs(s.Before.Mean-0.6) > 0.05 || math.Abs(s.After.Mean-2.1) > 0.05 {
+		t.Errorf("suspend = %+v", s)
+	}
+
+	// No change across a split inside the same version
+	same := CompareDrainAt(rows[:len(rows)/2], t0.Add(24*time.Hour), 5)
+	if same.Regression() {
+		t.Errorf("regression within one version: %+v", same)
+	}
+}
+
+func TestCompareDrainAtFewSessions(t *testing.T) {
+	// One long session a side: hundreds of minute intervals, but only one
+	// sample each, so nothing is tested
+	t0 := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	upgrade := t0.Add(24 * time.Hour)
+	var rows []Row
+	for _, start := range []time.Time{t0, upgrade} {
+		power := 8.0
+		if start == upgrade {
+			power = 11
+		}
+		for m := 0; m <= 300; m++ {
+			rows = append(rows, Row{T: start.Add(time.Duration(m) * time.Minute), Batt: 100 - float64(m/6),
+				PowerW: power + float64(m%3) - 1, UptimeSecs: math.NaN(), CPUUtil: math.NaN()})
+		}
+	}
+	d := CompareDrainAt(rows, upgrade, 5).Discharge
+	if d.Before.N != 1 || d.After.N != 1 || !math.IsNaN(d.PValue) || d.Regression {
+		t.Errorf("discharge = %+v", d)
+	}
+}
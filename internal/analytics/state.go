@@ -0,0 +1,49 @@
+package analytics
+
+import "strings"
+
+// State is the battery charge state as reported by the kernel's status
+// attribute
+type State int
+
+const (
+	StateUnknown     State = iota // Unknown, or a log without a status column
+	StateCharging                 // Taking charge from an external supply
+	StateDischarging              // Running on battery
+	StateNotCharging              // Plugged in but held, e.g. at a charge limit
+	StateFull                     // Plugged in and full
+)
+
+// ParseState maps a power_supply status string to a State
+func ParseState(status string) State {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "charging":
+		return StateCharging
+	case "discharging":
+		return StateDischarging
+	case "not charging":
+		return StateNotCharging
+	case "full":
+		return StateFull
+	}
+	return StateUnknown
+}
+
+func (s State) String() string {
+	switch s {
+	case StateCharging:
+		return "Charging"
+	case StateDischarging:
+		return "Discharging"
+	case StateNotCharging:
+		return "Not charging"
+	case StateFull:
+		return "Full"
+	}
+	return "Unknown"
+}
+
+// Held reports whether the state means plugged in without taking charge
+func (s State) Held() bool {
+	return s == StateNotCharging || s == StateFull
+}
@@ -0,0 +1,36 @@
+package analytics
+
+import "testing"
+
+func TestParseState(t *testing.T) {
+	cases := map[string]State{
+		"Charging":     StateCharging,
+		"Discharging":  StateDischarging,
+		"Not charging": StateNotCharging,
+		" full ":       StateFull,
+		"Unknown":      StateUnknown,
+		"":             StateUnknown,
+	}
+	for in, want := range cases {
+		if got := ParseState(in); got != want {
+			t.Errorf("ParseState(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestParseCSVRowsState(t *testing.T) {
+	rows, err := ParseCSVRows([][]string{
+		{"timestamp", "ac_connected", "battery_life", "status"},
+		{"2024-01-01T10:00:00Z", "1", "80", "Not charging"},
+		{"2024-01-01T10:01:00Z", "0", "79", "Discharging"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].State != StateNotCharging || rows[1].State != StateDischarging {
+		t.Fatalf("states = %v, %v", rows[0].State, rows[1].State)
+	}
+	if !IsHeldAtLimit(rows[0], 100) {
+		t.Error("Not charging row should be held")
+	}
+}
@@ -0,0 +1,218 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSuspendAccounting(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	row := func(min int, suspended float64) Row {
+		return Row{T: t0.Add(time.Duration(min) * time.Minute), Batt: 80, ScreenOn: 1, IdleSecs: -1, SuspendedSecs: suspended}
+	}
+	rows := []Row{
+		row(0, 100),
+		row(1, 100),
+		row(4, 280), // 3-minute suspend inside a short gap
+		row(5, 280),
+		row(20, 280), // Stalled daemon: 15 minutes awake
+		row(21, 280),
+	}
+	events := DetectSuspendEvents(rows, 5)
+	if len(events) != 2 || events[0].Kind != KindSuspend || !events[0].Measured || events[0].Duration != 3*time.Minute {
+		t.Fatalf("events = %+v", events)
+	}
+	if events[1].Kind != KindDaemonDown || events[1].Duration != 15*time.Minute {
+		t.Errorf("stalled daemon = %+v", events[1])
+	}
+	sot := CalculateScreenOnTime(rows, 5)
+	if sot.SuspendTime != 3*time.Minute || sot.TotalActiveTime != 18*time.Minute {
+		t.Errorf("suspend %v, active %v", sot.SuspendTime, sot.TotalActiveTime)
+	}
+	if sot.LastActiveSession != 17*time.Minute {
+		t.Errorf("last session = %v", sot.LastActiveSession)
+	}
+}
+
+func TestSuspendFallsBackToGaps(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	rows := []Row{
+		{T: t0, Batt: 80, SuspendedSecs: math.NaN()},
+		{T: t0.Add(10 * time.Minute), Batt: 78, SuspendedSecs: 50},
+		// suspended_secs went backwards: a reboot, so the gap decides
+		{T: t0.Add(30 * time.Minute), Batt: 70, SuspendedSecs: 0},
+	}
+	events := DetectSuspendEvents(rows, 5)
+	if len(events) != 2 || events[0].Measured || events[0].Kind != KindSuspend {
+		t.Fatalf("events = %+v", events)
+	}
+	if events[1].Kind != KindShutdown || events[1].Duration != 20*time.Minute {
+		t.Errorf("reboot = %+v", events[1])
+	}
+}
+
+func TestShutdownWithoutSuspends(t *testing.T) {
+	// A machine that never suspends: suspended_secs is 0 on both sides of
+	// the power-off, so only the gap shows it
+	t0 := time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+	rows := []Row{
+		{T: t0, Batt: 60, ScreenOn: 1, SuspendedSecs: 0, UptimeSecs: math.NaN()},
+		{T: t0.Add(time.Minute), Batt: 60, ScreenOn: 1, SuspendedSecs: 0, UptimeSecs: math.NaN()},
+		{T: t0.Add(14 * time.Hour), Batt: 58, ScreenOn: 1, SuspendedSecs: 0, UptimeSecs: math.NaN()},
+	}
+	events := DetectSuspendEvents(rows, 5)
+	if len(events) != 1 || events[0].Kind != KindShutdown || events[0].Duration != 14*time.Hour-time.Minute {
+		t.Fatalf("events = %+v", events)
+	}
+	if !awakeBetween(rows[0], rows[1], 5*time.Minute) || awakeBetween(rows[1], rows[2], 5*time.Minute) {
+		t.Error("awakeBetween() counted the power-off as awake or the minute as asleep")
+	}
+	if sot := CalculateScreenOnTime(rows, 5); sot.TotalActiveTime != time.Minute {
+		t.Errorf("active = %v, want 1m", sot.TotalActiveTime)
+	}
+}
+
+func TestSuspendKinds(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+	row := func(at time.Duration, batt float64, boot string, suspended, uptime float64) Row {
+		return Row{T: t0.Add(at), Batt: batt, BootID: boot, SuspendedSecs: suspended, UptimeSecs: uptime}
+	}
+	rows := []Row{
+		row(0, 90, "a", 0, 3600),
+		// Overnight: 8h asleep, nothing drained, so likely hibernate
+		row(8*time.Hour+time.Minute, 90, "a", 8*3600, 3600+8*3600+60),
+		// Powered off; booted 2 minutes before this sample
+		row(10*time.Hour, 80, "b", 0, 120),
+		// 2h asleep with 6% drained: suspend to RAM
+		row(12*time.Hour+time.Minute, 74, "b", 7200, 120+7200+60),
+	}
+	events := DetectSuspendEvents(rows, 5)
+	want := []struct {
+		kind SuspendKind
+		dur  time.Duration
+	}{
+		{KindLikelyHibernate, 8 * time.Hour},
+		{KindShutdown, 10*time.Hour - 2*time.Minute - (8*time.Hour + time.Minute)},
+		{KindSuspend, 2 * time.Hour},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v", events)
+	}
+	for i, w := range want {
+		if events[i].Kind != w.kind || events[i].Duration != w.dur || !events[i].Measured {
+			t.Errorf("event %d = %s %v, want %s %v", i, events[i].Kind, events[i].Duration, w.kind, w.dur)
+		}
+	}
+	if last := LastSleep(events); last == nil || last.Kind != KindSuspend {
+		t.Errorf("LastSleep() = %+v", last)
+	}
+}
+
+func TestSleepKind(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		ac    bool
+		slept time.Duration
+		drop  float64
+		want  SuspendKind
+	}{
+		{"good S3 night", false, 8 * time.Hour, 1, KindSuspend},
+		{"no drain overnight", false, 8 * time.Hour, 0, KindLikelyHibernate},
+		{"short nap", false, 30 * time.Minute, 0, KindSuspend},
+		{"on AC", true, 8 * time.Hour, 0, KindSuspend},
+	}
+	for _, c := range cases {
+		prev := Row{T: t0, AC: c.ac, Batt: 80}
+		cur := Row{T: t0.Add(c.slept), AC: c.ac, Batt: 80 - c.drop}
+		if got := sleepKind(prev, cur, c.slept); got != c.want {
+			t.Errorf("%s: sleepKind() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRebooted(t *testing.T) {
+	nan := math.NaN()
+	row := func(boot string, suspended, uptime float64) Row {
+		return Row{BootID: boot, SuspendedSecs: suspended, UptimeSecs: uptime}
+	}
+	cases := []struct {
+		name      string
+		prev, cur Row
+		want      bool
+	}{
+		{"boot ID changed", row("a", 0, 100), row("b", 0, 200), true},
+		{"same boot ID", row("a", 50, 100), row("a", 50, 200), false},
+		// The boot ID is trusted over the counters
+		{"same boot ID, uptime fell", row("a", 0, 200), row("a", 0, 100), false},
+		{"uptime fell", row("", 0, 3600), row("", 0, 120), true},
+		{"suspended fell", row("", 600, nan), row("", 0, nan), true},
+		{"counters grew", row("", 0, 3600), row("", 60, 3720), false},
+		{"one side without boot ID", row("a", 0, 3600), row("", 0, 120), true},
+		{"no columns", row("", nan, nan), row("", nan, nan), false},
+	}
+	for _, c := range cases {
+		if got := rebooted(c.prev, c.cur); got != c.want {
+			t.Errorf("%s: rebooted() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBootSessions(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	row := func(min int, batt float64, boot string, suspended, uptime float64) Row {
+		return Row{T: t0.Add(time.Duration(min) * time.Minute), Batt: batt, BootID: boot,
+			SuspendedSecs: suspended, UptimeSecs: uptime, EnergyNow: math.NaN()}
+	}
+	rows := []Row{
+		{T: t0.Add(-time.Hour), Batt: 100, SuspendedSecs: math.NaN(), UptimeSecs: math.NaN()}, // Older row: skipped
+		row(0, 100, "a", 0, 600),
+		row(1, 99, "a", 0, 660),
+		row(32, 97, "a", 1800, 2520),
+		row(33, 98, "a", 1800, 2580),
+		row(60, 50, "b", 0, 60),
+	}
+	got := BootSessions(rows, 5)
+	if len(got) != 2 {
+		t.Fatalf("sessions = %+v", got)
+	}
+	a := got[0]
+	if a.Boot != t0.Add(-10*time.Minute) || a.Samples != 4 || a.Asleep != 30*time.Minute {
+		t.Errorf("session a = %+v", a)
+	}
+	if a.Used != 3 || a.Charged != 1 || a.Awake() != 13*time.Minute || !math.IsNaN(a.UsedWh) {
+		t.Errorf("session a usage: used %v, charged %v, awake %v, wh %v", a.Used, a.Charged, a.Awake(), a.UsedWh)
+	}
+	if got[1].BootID != "b" || got[1].Boot != t0.Add(59*time.Minute) {
+		t.Errorf("session b = %+v", got[1])
+	}
+}
+
+func TestDockedScreenTime(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	row := func(min, lid, displays int) Row {
+		return Row{T: t0.Add(time.Duration(min) * time.Minute), Batt: 80, ScreenOn: 1, IdleSecs: -1,
+			SuspendedSecs: math.NaN(), UptimeSecs: math.NaN(), LidOpen: lid, ExternalDisplays: displays}
+	}
+	rows := []Row{
+		row(0, 1, 0),
+		row(2, 0, 1), // Closed on a monitor: docked
+		row(4, 0, 1),
+		row(5, 1, 1), // Lid open with a monitor still counts as mobile
+		row(6, -1, -1),
+	}
+	sot := CalculateScreenOnTime(rows, 5)
+	if sot.DockedTime != 3*time.Minute || sot.MobileTime != 3*time.Minute {
+		t.Errorf("docked %v, mobile %v", sot.DockedTime, sot.MobileTime)
+	}
+	if got := MobileRows(rows); len(got) != 3 {
+		t.Errorf("MobileRows() kept %d rows", len(got))
+	}
+	if got := TrailingMobile(rows); len(got) != 2 || got[0].T != rows[3].T {
+		t.Errorf("TrailingMobile() = %+v", got)
+	}
+	if got := TrailingMobile(rows[:3]); len(got) != 0 {
+		t.Errorf("TrailingMobile() while docked = %+v", got)
+	}
+}
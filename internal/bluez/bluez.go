@@ -0,0 +1,100 @@
+// Package bluez reads the battery level of connected Bluetooth devices from
+// BlueZ's org.bluez.Battery1 interface on the system bus.
+package bluez
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	service           = "org.bluez"
+	deviceIface       = "org.bluez.Device1"
+	batteryIface      = "org.bluez.Battery1"
+	getManagedObjects = "org.freedesktop.DBus.ObjectManager.GetManagedObjects"
+)
+
+// Battery is the reported level of one Bluetooth device
+type Battery struct {
+	Path    dbus.ObjectPath
+	Address string // MAC address, e.g. AA:BB:CC:DD:EE:FF
+	Name    string // User-visible alias, falling back to the device name
+	Icon    string // Device class hint, e.g. audio-headset, input-mouse
+	Percent int
+}
+
+// ManagedObjects is the reply of org.freedesktop.DBus.ObjectManager.GetManagedObjects
+type ManagedObjects = map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// Connect opens the system bus, or the bus at address if non-empty. The
+// system bus honours DBUS_SYSTEM_BUS_ADDRESS, which is how a stand-in bus
+// is selected during development.
+func Connect(address string) (*dbus.Conn, error) {
+	if address == "" {
+		return dbus.ConnectSystemBus()
+	}
+	return dbus.Connect(address)
+}
+
+// Batteries lists every BlueZ device that exposes Battery1, ordered by
+// address. A bus without BlueZ running yields no batteries and no error.
+func Batteries(conn *dbus.Conn) ([]Battery, error) {
+	var objs ManagedObjects
+	err := conn.Object(service, "/").Call(getManagedObjects, 0).Store(&objs)
+	if err != nil {
+		var dbusErr dbus.Error
+		if errors.As(err, &dbusErr) && dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("bluez: %w", err)
+	}
+	return parseObjects(objs), nil
+}
+
+func parseObjects(objs ManagedObjects) []Battery {
+	var out []Battery
+	for path, ifaces := range objs {
+		bat, ok := ifaces[batteryIface]
+		if !ok {
+			continue
+		}
+		pct, ok := bat["Percentage"].Value().(byte)
+		if !ok {
+			continue
+		}
+		b := Battery{Path: path, Percent: int(pct)}
+		dev := ifaces[deviceIface]
+		b.Address = stringProp(dev, "Address")
+		b.Icon = stringProp(dev, "Icon")
+		b.Name = stringProp(dev, "Alias")
+		if b.Name == "" {
+			b.Name = stringProp(dev, "Name")
+		}
+		if b.Address == "" {
+			b.Address = addressFromPath(path)
+		}
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Address < out[j].Address })
+	return out
+}
+
+func stringProp(props map[string]dbus.Variant, name string) string {
+	s, _ := props[name].Value().(string)
+	return s
+}
+
+// addressFromPath recovers the MAC from a device path such as
+// /org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF.
+func addressFromPath(path dbus.ObjectPath) string {
+	s := string(path)
+	i := strings.LastIndex(s, "/dev_")
+	if i < 0 {
+		return ""
+	}
+	return strings.ReplaceAll(s[i+len("/dev_"):], "_", ":")
+}
@@ -0,0 +1,129 @@
+package bluez
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// privateBus starts a throwaway dbus-daemon and returns its address
+func privateBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(conf, []byte(`<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=`+filepath.Join(dir, "bus")+`</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+conf, "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("starting dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+// fakeBlueZ serves a fixed GetManagedObjects reply as org.bluez
+type fakeBlueZ struct {
+	objs ManagedObjects
+}
+
+func (f fakeBlueZ) GetManagedObjects() (ManagedObjects, *dbus.Error) {
+	return f.objs, nil
+}
+
+func connect(t *testing.T, addr string) *dbus.Conn {
+	t.Helper()
+	conn, err := Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBatteries(t *testing.T) {
+	addr := privateBus(t)
+
+	server := connect(t, addr)
+	fake := fakeBlueZ{objs: ManagedObjects{
+		"/org/bluez/hci0": {
+			"org.bluez.Adapter1": {"Address": dbus.MakeVariant("00:11:22:33:44:55")},
+		},
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF": {
+			deviceIface: {
+				"Address": dbus.MakeVariant("AA:BB:CC:DD:EE:FF"),
+				"Name":    dbus.MakeVariant("WH-1000XM4"),
+				"Alias":   dbus.MakeVariant("Headphones"),
+				"Icon":    dbus.MakeVariant("audio-headset"),
+			},
+			batteryIface: {"Percentage": dbus.MakeVariant(byte(42))},
+		},
+		"/org/bluez/hci0/dev_11_22_33_44_55_66": {
+			deviceIface: {"Address": dbus.MakeVariant("11:22:33:44:55:66"), "Name": dbus.MakeVariant("Keyboard")},
+		},
+	}}
+	if err := server.Export(fake, "/", "org.freedesktop.DBus.ObjectManager"); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := server.RequestName(service, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName() = %v, %v", reply, err)
+	}
+
+	got, err := Batteries(connect(t, addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Batteries() = %+v", got)
+	}
+	b := got[0]
+	if b.Address != "AA:BB:CC:DD:EE:FF" || b.Name != "Headphones" || b.Icon != "audio-headset" || b.Percent != 42 {
+		t.Errorf("Batteries()[0] = %+v", b)
+	}
+}
+
+func TestBatteriesWithoutBlueZ(t *testing.T) {
+	got, err := Batteries(connect(t, privateBus(t)))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Batteries() = %+v, %v; want none", got, err)
+	}
+}
+
+func TestAddressFromPath(t *testing.T) {
+	if got := addressFromPath("/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF"); got != "AA:BB:CC:DD:EE:FF" {
+		t.Errorf("addressFromPath() = %q", got)
+	}
+}
@@ -0,0 +1,27 @@
+// Package clock reads the kernel clocks used for suspend accounting.
+package clock
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Suspended returns the total time the system has spent suspended since
+// boot. CLOCK_BOOTTIME keeps counting through suspend while
+// CLOCK_MONOTONIC stops, so their difference is the time asleep.
+func Suspended() (time.Duration, error) {
+	var mono, boot unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &mono); err != nil {
+		return 0, fmt.Errorf("clock_gettime(CLOCK_MONOTONIC): %w", err)
+	}
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &boot); err != nil {
+		return 0, fmt.Errorf("clock_gettime(CLOCK_BOOTTIME): %w", err)
+	}
+	d := time.Duration(boot.Nano() - mono.Nano())
+	if d < 0 {
+		d = 0
+	}
+	return d, nil
+}
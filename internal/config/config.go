@@ -18,6 +18,10 @@ type Config struct {
 	LogFile           string `toml:"log_file"`
 	MaxLines          int    `toml:"max_lines"`
 	TrimBuffer        int    `toml:"trim_buffer"`
+	ArchiveLogs       bool   `toml:"archive_logs"`      // Move trimmed rows to monthly .csv.gz archives
+	Storage           string `toml:"storage"`           // Sample log backend: csv or segment
+	SyncWrites        bool   `toml:"sync_writes"`       // fsync the log after every append
+	LockTimeoutSecs   int    `toml:"lock_timeout_secs"` // Wait for another process using the log
 	MaxChargePercent  int    `toml:"max_charge_percent"`
 	DayColorNumber    int    `toml:"day_color_number"`
 	NightColorNumber  int    `toml:"night_color_number"`
@@ -25,8 +29,39 @@ type Config struct {
 	DayEndHour        int    `toml:"day_end_hour"`
 	MaxWindowZoom     int    `toml:"max_window_zoom"`     // Maximum zoom window in days
 	SuspendGapMinutes int    `toml:"suspend_gap_minutes"` // Consider gaps >= this as suspend/shutdown
+	HealthFile        string `toml:"health_file"`         // Capacity history, kept across trims
+	HealthTarget      int    `toml:"health_target_percent"`
+	SysfsRoot         string `toml:"sysfs_root"` // sysfs mount point; override to read a fake tree
+
+	// Peripheral batteries (mice, keyboards, headsets), logged separately
+	PeripheralFile       string `toml:"peripheral_file"`
+	PeripheralLowPercent int    `toml:"peripheral_low_percent"` // Warn below this level; 0 disables
+
+	ProcessFile string `toml:"process_file"` // Per-interval CPU time of top processes
+
+	SuspendFile         string  `toml:"suspend_file"`          // Wakeup sources per suspend
+	AbnormalDrainFactor float64 `toml:"abnormal_drain_factor"` // Flag nights draining this many times the median
+
+	VersionFile          string `toml:"version_file"`           // Kernel, BIOS and battery model changes
+	RegressionWindowDays int    `toml:"regression_window_days"` // Days compared on each side of a version change
+
+	// Platform profile switching by "run"; empty profiles leave it untouched
+	ProfileOnAC         string `toml:"profile_on_ac"`
+	ProfileOnBattery    string `toml:"profile_on_battery"`
+	ProfileLowBattery   string `toml:"profile_low_battery"`
+	ProfileLowBatteryAt int    `toml:"profile_low_battery_percent"`
+
+	// Charge control, applied by "run" on start when ApplyChargeSettings is set.
+	// Thresholds of -1 and an empty behaviour leave the firmware untouched.
+	ChargeStartThreshold int    `toml:"charge_start_threshold"`
+	ChargeEndThreshold   int    `toml:"charge_end_threshold"`
+	ChargeBehaviour      string `toml:"charge_behaviour"`
+	ApplyChargeSettings  bool   `toml:"apply_charge_settings"`
 }
 
+// SysfsRootEnv overrides sysfs_root from the environment
+const SysfsRootEnv = "BATTERY_ZEN_SYSFS_ROOT"
+
 func Defaults() Config {
 	return Config{
 		IntervalSecs:      60,
@@ -36,6 +71,10 @@ func Defaults() Config {
 		LogFile:           "logs.csv",
 		MaxLines:          4000,
 		TrimBuffer:        100,
+		ArchiveLogs:       true,
+		Storage:           "csv",
+		SyncWrites:        true,
+		LockTimeoutSecs:   10,
 		MaxChargePercent:  100,
 		DayColorNumber:    237, // Dark gray for day
 		NightColorNumber:  0,   // True black for night
@@ -43,6 +82,25 @@ func Defaults() Config {
 		DayEndHour:        19,  // 7 PM
 		MaxWindowZoom:     10,  // Maximum zoom window in days
 		SuspendGapMinutes: 5,   // Default 5 minutes gap detection
+		HealthFile:        "health.csv",
+		HealthTarget:      80, // Replacement threshold for health projections
+		SysfsRoot:         "/sys",
+
+		PeripheralFile:       "peripherals.csv",
+		PeripheralLowPercent: 20,
+
+		ProcessFile: "processes.csv",
+
+		SuspendFile:         "suspends.csv",
+		AbnormalDrainFactor: 2,
+
+		VersionFile:          "versions.csv",
+		RegressionWindowDays: 14,
+
+		ProfileLowBatteryAt: 20,
+
+		ChargeStartThreshold: -1,
+		ChargeEndThreshold:   -1,
 	}
 }
 
@@ -98,6 +156,10 @@ func Load() (Config, error) {
 		}
 	}
 
+	if v := os.Getenv(SysfsRootEnv); v != "" {
+		cfg.SysfsRoot = v
+	}
+
 	// Expand ~ in LogDir
 	if strings.HasPrefix(cfg.LogDir, "~") {
 		home, _ := os.UserHomeDir()
@@ -166,6 +228,14 @@ func setConfigValue(key, value string, cfg *Config) error {
 		return parseIntValue(value, &cfg.MaxLines)
 	case "trim_buffer":
 		return parseIntValue(value, &cfg.TrimBuffer)
+	case "archive_logs":
+		return parseBoolValue(value, &cfg.ArchiveLogs)
+	case "storage":
+		cfg.Storage = value
+	case "sync_writes":
+		return parseBoolValue(value, &cfg.SyncWrites)
+	case "lock_timeout_secs":
+		return parseIntValue(value, &cfg.LockTimeoutSecs)
 	case "max_charge_percent":
 		return parseIntValue(value, &cfg.MaxChargePercent)
 	case "day_color_number":
@@ -180,6 +250,42 @@ func setConfigValue(key, value string, cfg *Config) error {
 		return parseIntValue(value, &cfg.MaxWindowZoom)
 	case "suspend_gap_minutes":
 		return parseIntValue(value, &cfg.SuspendGapMinutes)
+	case "health_file":
+		cfg.HealthFile = value
+	case "health_target_percent":
+		return parseIntValue(value, &cfg.HealthTarget)
+	case "sysfs_root":
+		cfg.SysfsRoot = value
+	case "peripheral_file":
+		cfg.PeripheralFile = value
+	case "peripheral_low_percent":
+		return parseIntValue(value, &cfg.PeripheralLowPercent)
+	case "process_file":
+		cfg.ProcessFile = value
+	case "suspend_file":
+		cfg.SuspendFile = value
+	case "abnormal_drain_factor":
+		return parseFloatValue(value, &cfg.AbnormalDrainFactor)
+	case "version_file":
+		cfg.VersionFile = value
+	case "regression_window_days":
+		return parseIntValue(value, &cfg.RegressionWindowDays)
+	case "profile_on_ac":
+		cfg.ProfileOnAC = value
+	case "profile_on_battery":
+		cfg.ProfileOnBattery = value
+	case "profile_low_battery":
+		cfg.ProfileLowBattery = value
+	case "profile_low_battery_percent":
+		return parseIntValue(value, &cfg.ProfileLowBatteryAt)
+	case "charge_start_threshold":
+		return parseIntValue(value, &cfg.ChargeStartThreshold)
+	case "charge_end_threshold":
+		return parseIntValue(value, &cfg.ChargeEndThreshold)
+	case "charge_behaviour":
+		cfg.ChargeBehaviour = value
+	case "apply_charge_settings":
+		return parseBoolValue(value, &cfg.ApplyChargeSettings)
 	}
 	return nil
 }
@@ -193,6 +299,24 @@ func parseIntValue(value string, target *int) error {
 	return nil
 }
 
+func parseFloatValue(value string, target *float64) error {
+	val, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*target = val
+	return nil
+}
+
+func parseBoolValue(value string, target *bool) error {
+	val, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*target = val
+	return nil
+}
+
 func XDGLogPath(cfg Config) (string, error) {
 	if _, err := os.Stat(cfg.LogDir); errors.Is(err, os.ErrNotExist) {
 		if err := os.MkdirAll(cfg.LogDir, 0o755); err != nil {
@@ -202,6 +326,31 @@ func XDGLogPath(cfg Config) (string, error) {
 	return filepath.Join(cfg.LogDir, cfg.LogFile), nil
 }
 
+// HealthPath returns the location of the battery health history file
+func HealthPath(cfg Config) string {
+	return filepath.Join(cfg.LogDir, cfg.HealthFile)
+}
+
+// PeripheralPath returns the location of the peripheral battery log
+func PeripheralPath(cfg Config) string {
+	return filepath.Join(cfg.LogDir, cfg.PeripheralFile)
+}
+
+// ProcessPath returns the location of the per-process CPU time log
+func ProcessPath(cfg Config) string {
+	return filepath.Join(cfg.LogDir, cfg.ProcessFile)
+}
+
+// SuspendPath returns the location of the per-suspend wakeup source log
+func SuspendPath(cfg Config) string {
+	return filepath.Join(cfg.LogDir, cfg.SuspendFile)
+}
+
+// VersionPath returns the location of the kernel and firmware version log
+func VersionPath(cfg Config) string {
+	return filepath.Join(cfg.LogDir, cfg.VersionFile)
+}
+
 func Now(cfg Config) time.Time {
 	if strings.EqualFold(cfg.Timezone, "Local") {
 		return time.Now()
@@ -0,0 +1,272 @@
+// Package consumers attributes battery drain to processes in proportion to
+// the CPU time they used, from compact per-interval summaries written by
+// the daemon.
+package consumers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+)
+
+var header = []string{"timestamp", "interval_secs", "ac_connected", "drain_w", "processes"}
+
+// Other collects the CPU time of processes outside an interval's top list
+const Other = "(other)"
+
+// Interval is the CPU time per process over one sampling interval, ending at T
+type Interval struct {
+	T        time.Time
+	Duration time.Duration
+	AC       bool
+	DrainW   float64            // Battery drain over the interval, NaN if unknown or on AC
+	CPU      map[string]float64 // CPU seconds per process name
+}
+
+// Clock measures sampling intervals in awake time. A suspend inside an
+// interval would otherwise stretch the drain read on resume over the whole
+// time asleep and charge it to whatever ran in the first minute awake.
+type Clock struct {
+	at        time.Time
+	suspended time.Duration
+}
+
+// Lap returns the time awake since the previous call: the wall-clock time
+// less the growth in time suspended since boot. The first call only starts
+// the clock.
+func (c *Clock) Lap(now time.Time, suspended time.Duration) (time.Duration, bool) {
+	prev, prevSuspended := c.at, c.suspended
+	c.at, c.suspended = now, suspended
+	if prev.IsZero() {
+		return 0, false
+	}
+	awake := now.Sub(prev)
+	if slept := suspended - prevSuspended; slept > 0 {
+		awake -= slept
+	}
+	return max(awake, 0), true
+}
+
+// NewInterval keeps the topN processes by CPU time and folds the rest into Other
+func NewInterval(t time.Time, d time.Duration, ac bool, drainW float64, cpu map[string]float64, topN int) Interval {
+	iv := Interval{T: t, Duration: d, AC: ac, DrainW: drainW, CPU: make(map[string]float64)}
+	names := make([]string, 0, len(cpu))
+	for name := range cpu {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return cpu[names[i]] > cpu[names[j]] })
+	for i, name := range names {
+		if i < topN {
+			iv.CPU[name] += cpu[name]
+		} else {
+			iv.CPU[Other] += cpu[name]
+		}
+	}
+	return iv
+}
+
+// sanitize keeps process names from breaking the name=value;... encoding
+var sanitize = strings.NewReplacer(";", "_", "=", "_", ",", "_", "\n", "_")
+
+func formatCPU(cpu map[string]float64) string {
+	names := make([]string, 0, len(cpu))
+	for name := range cpu {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return cpu[names[i]] > cpu[names[j]] })
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%.2f", sanitize.Replace(name), cpu[name])
+	}
+	return strings.Join(parts, ";")
+}
+
+func parseCPU(s string) map[string]float64 {
+	cpu := make(map[string]float64)
+	for _, part := range strings.Split(s, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseFloat(val, 64); err == nil {
+			cpu[name] += v
+		}
+	}
+	return cpu
+}
+
+// Store is the per-interval process log
+type Store struct {
+	Path string
+}
+
+func (s *Store) table() *logfile.Table {
+	return &logfile.Table{Path: s.Path, Header: header}
+}
+
+// Load reads all intervals in file order. A missing file yields none.
+func (s *Store) Load() ([]Interval, error) {
+	recs, err := s.table().Load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Interval
+	for _, rec := range recs {
+		t, err := time.Parse(time.RFC3339, rec[0])
+		if err != nil {
+			continue
+		}
+		secs, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			continue
+		}
+		drain, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			drain = math.NaN()
+		}
+		out = append(out, Interval{
+			T:        t,
+			Duration: time.Duration(secs * float64(time.Second)),
+			AC:       rec[2] == "1",
+			DrainW:   drain,
+			CPU:      parseCPU(rec[4]),
+		})
+	}
+	return out, nil
+}
+
+// Append writes one interval, creating the file with a header if needed
+func (s *Store) Append(iv Interval) error {
+	ac := "0"
+	if iv.AC {
+		ac = "1"
+	}
+	drain := ""
+	if !math.IsNaN(iv.DrainW) {
+		drain = strconv.FormatFloat(iv.DrainW, 'f', 3, 64)
+	}
+	return s.table().Append([]string{
+		iv.T.Format(time.RFC3339),
+		strconv.FormatFloat(iv.Duration.Seconds(), 'f', 0, 64),
+		ac, drain, formatCPU(iv.CPU),
+	})
+}
+
+// Trim keeps the last maxRows intervals once the file is buffer rows past that
+func (s *Store) Trim(maxRows, buffer int) error {
+	return s.table().Trim(maxRows, buffer)
+}
+
+// FillDrain estimates the drain of battery intervals that lack a power
+// reading from the regression rate of the surrounding discharge in rows,
+// converted to watts with the pack's full energy.
+func FillDrain(intervals []Interval, rows []analytics.Row, window time.Duration) {
+	for i := range intervals {
+		iv := &intervals[i]
+		if iv.AC || !math.IsNaN(iv.DrainW) {
+			continue
+		}
+		var seg []analytics.Row
+		for _, r := range rows {
+			if !r.AC && !r.T.Before(iv.T.Add(-window)) && !r.T.After(iv.T) {
+				seg = append(seg, r)
+			}
+		}
+		if len(seg) < 2 || math.IsNaN(seg[len(seg)-1].EnergyFull) {
+			continue
+		}
+		// Equal weights: the window is already local to the interval
+		slope, _, ok := analytics.WeightedLinReg(seg, 0)
+		if !ok || slope >= 0 {
+			continue
+		}
+		iv.DrainW = -slope * 60 / 100 * seg[len(seg)-1].EnergyFull
+	}
+}
+
+// Consumer is the total CPU time and attributed battery energy of one process name
+type Consumer struct {
+	Name     string
+	CPUSecs  float64 // On battery and on AC
+	EnergyWh float64 // Share of battery drain, 0 if it only ran on AC
+	Share    float64 // Fraction of all attributed energy
+}
+
+// Attribute splits each battery interval's energy (drain × duration) across
+// processes by their share of the interval's CPU time, and sums per name,
+// largest energy first. The second result is the total energy attributed.
+func Attribute(intervals []Interval) ([]Consumer, float64) {
+	byName := make(map[string]*Consumer)
+	get := func(name string) *Consumer {
+		c, ok := byName[name]
+		if !ok {
+			c = &Consumer{Name: name}
+			byName[name] = c
+		}
+		return c
+	}
+
+	var total float64
+	for _, iv := range intervals {
+		var cpuTotal float64
+		for name, secs := range iv.CPU {
+			get(name).CPUSecs += secs
+			cpuTotal += secs
+		}
+		if iv.AC || math.IsNaN(iv.DrainW) || cpuTotal == 0 {
+			continue
+		}
+		energy := iv.DrainW * iv.Duration.Hours()
+		total += energy
+		for name, secs := range iv.CPU {
+			get(name).EnergyWh += energy * secs / cpuTotal
+		}
+	}
+
+	out := make([]Consumer, 0, len(byName))
+	for _, c := range byName {
+		if total > 0 {
+			c.Share = c.EnergyWh / total
+		}
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].EnergyWh != out[j].EnergyWh {
+			return out[i].EnergyWh > out[j].EnergyWh
+		}
+		return out[i].CPUSecs > out[j].CPUSecs
+	})
+	return out, total
+}
+
+// drainWindow is how far back FillDrain looks for the discharge rate
+const drainWindow = 15 * time.Minute
+
+// Report attributes the drain of the intervals in path since t, estimating
+// intervals without a power reading from the main log rows
+func Report(path string, rows []analytics.Row, since time.Time) ([]Consumer, float64, error) {
+	intervals, err := (&Store{Path: path}).Load()
+	if err != nil {
+		return nil, 0, err
+	}
+	intervals = Since(intervals, since)
+	FillDrain(intervals, rows, drainWindow)
+	list, total := Attribute(intervals)
+	return list, total, nil
+}
+
+// Since returns the intervals ending at or after t
+func Since(intervals []Interval, t time.Time) []Interval {
+	for i, iv := range intervals {
+		if !iv.T.Before(t) {
+			return intervals[i:]
+		}
+	}
+	return nil
+}
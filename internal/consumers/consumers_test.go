@@ -0,0 +1,85 @@
+package consumers
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewIntervalFoldsTail(t *testing.T) {
+	iv := NewInterval(time.Time{}, time.Minute, false, 10, map[string]float64{
+		"firefox": 30, "cc1plus": 20, "bash": 1, "sshd": 0.5,
+	}, 2)
+	if len(iv.CPU) != 3 || iv.CPU[Other] != 1.5 || iv.CPU["firefox"] != 30 {
+		t.Errorf("NewInterval().CPU = %v", iv.CPU)
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "processes.csv")}
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	in := []Interval{
+		{T: t0, Duration: time.Minute, DrainW: 8.5, CPU: map[string]float64{"Web Content": 12.5, "a;b=c": 1}},
+		{T: t0.Add(time.Minute), Duration: time.Minute, AC: true, DrainW: math.NaN(), CPU: map[string]float64{"make": 3}},
+	}
+	for _, iv := range in {
+		if err := s.Append(iv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.Load()
+	if err != nil || len(got) != 2 {
+		t.Fatalf("Load() = %+v, %v", got, err)
+	}
+	if got[0].DrainW != 8.5 || got[0].CPU["Web Content"] != 12.5 || got[0].CPU["a_b_c"] != 1 {
+		t.Errorf("Load()[0] = %+v", got[0])
+	}
+	if !got[1].AC || !math.IsNaN(got[1].DrainW) || got[1].Duration != time.Minute {
+		t.Errorf("Load()[1] = %+v", got[1])
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	intervals := []Interval{
+		// 12 W for 30 min = 6 Wh, split 2:1
+		{Duration: 30 * time.Minute, DrainW: 12, CPU: map[string]float64{"firefox": 20, "make": 10}},
+		// On AC: CPU time counts, energy does not
+		{Duration: 30 * time.Minute, AC: true, DrainW: math.NaN(), CPU: map[string]float64{"make": 100}},
+	}
+	got, total := Attribute(intervals)
+	if total != 6 || len(got) != 2 {
+		t.Fatalf("Attribute() = %+v, %v", got, total)
+	}
+	if got[0].Name != "firefox" || got[0].EnergyWh != 4 || math.Abs(got[0].Share-2.0/3) > 1e-9 {
+		t.Errorf("firefox = %+v", got[0])
+	}
+	if got[1].Name != "make" || got[1].EnergyWh != 2 || got[1].CPUSecs != 110 {
+		t.Errorf("make = %+v", got[1])
+	}
+}
+
+func TestClockSkipsSuspend(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	var c Clock
+	if _, ok := c.Lap(t0, time.Hour); ok {
+		t.Fatal("first Lap() returned an interval")
+	}
+	if d, ok := c.Lap(t0.Add(time.Minute), time.Hour); !ok || d != time.Minute {
+		t.Errorf("awake interval = %v, %v", d, ok)
+	}
+	// Suspended for 8h a few seconds into the next interval
+	d, ok := c.Lap(t0.Add(8*time.Hour+2*time.Minute), 9*time.Hour)
+	if !ok || d != time.Minute {
+		t.Fatalf("interval with a suspend = %v, %v", d, ok)
+	}
+	// 10 W on resume is charged for the minute awake, not the night
+	iv := NewInterval(t0.Add(8*time.Hour+2*time.Minute), d, false, 10, map[string]float64{"firefox": 5}, 10)
+	if _, total := Attribute([]Interval{iv}); math.Abs(total-10.0/60) > 1e-9 {
+		t.Errorf("energy after resume = %v Wh", total)
+	}
+	// A clock that cannot be read leaves wall-clock time
+	if d, _ := c.Lap(t0.Add(8*time.Hour+4*time.Minute), 0); d != 2*time.Minute {
+		t.Errorf("interval without the suspend clock = %v", d)
+	}
+}
@@ -0,0 +1,222 @@
+// Package health records battery capacity snapshots and tracks how the full
+// charge capacity drifts away from the design capacity over time.
+package health
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+)
+
+var header = []string{"timestamp", "battery", "energy_full_wh", "energy_full_design_wh", "cycle_count"}
+
+// Snapshot is the capacity of one pack at a point in time
+type Snapshot struct {
+	T                time.Time
+	Pack             string
+	EnergyFull       float64 // Wh
+	EnergyFullDesign float64 // Wh
+	CycleCount       int     // -1 if unknown
+}
+
+// Percent returns full capacity as a percentage of design capacity
+func (s Snapshot) Percent() float64 {
+	if !(s.EnergyFullDesign > 0) {
+		return math.NaN()
+	}
+	return s.EnergyFull / s.EnergyFullDesign * 100
+}
+
+// Store is the append-only health history file. It is kept apart from the
+// sample log so it survives trimming.
+type Store struct {
+	Path string
+}
+
+func (s *Store) table() *logfile.Table {
+	return &logfile.Table{Path: s.Path, Header: header}
+}
+
+// Load reads all snapshots in file order. A missing file yields no snapshots.
+func (s *Store) Load() ([]Snapshot, error) {
+	recs, err := s.table().Load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Snapshot
+	for _, rec := range recs {
+		t, err := time.Parse(time.RFC3339, rec[0])
+		if err != nil {
+			continue
+		}
+		full, err1 := strconv.ParseFloat(rec[2], 64)
+		design, err2 := strconv.ParseFloat(rec[3], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		cycles, err := strconv.Atoi(rec[4])
+		if err != nil {
+			cycles = -1
+		}
+		out = append(out, Snapshot{T: t, Pack: rec[1], EnergyFull: full, EnergyFullDesign: design, CycleCount: cycles})
+	}
+	return out, nil
+}
+
+// Append writes one snapshot, creating the file with a header if needed
+func (s *Store) Append(snap Snapshot) error {
+	cycles := ""
+	if snap.CycleCount >= 0 {
+		cycles = strconv.Itoa(snap.CycleCount)
+	}
+	return s.table().Append([]string{
+		snap.T.Format(time.RFC3339), snap.Pack,
+		strconv.FormatFloat(snap.EnergyFull, 'f', 3, 64),
+		strconv.FormatFloat(snap.EnergyFullDesign, 'f', 3, 64),
+		cycles,
+	})
+}
+
+// Record appends the snapshots that differ meaningfully from the last
+// recorded one for the same pack: a change of more than 0.5% of design
+// capacity, a new cycle count, or a day since the last entry.
+func (s *Store) Record(snaps []Snapshot) error {
+	history, err := s.Load()
+	if err != nil {
+		return err
+	}
+	last := make(map[string]Snapshot)
+	for _, h := range history {
+		last[h.Pack] = h
+	}
+
+	for _, snap := range snaps {
+		if !(snap.EnergyFull > 0) || !(snap.EnergyFullDesign > 0) {
+			continue
+		}
+		if prev, ok := last[snap.Pack]; ok {
+			drift := math.Abs(snap.EnergyFull-prev.EnergyFull) / snap.EnergyFullDesign
+			if drift <= 0.005 && snap.CycleCount == prev.CycleCount && snap.T.Sub(prev.T) < 24*time.Hour {
+				continue
+			}
+		}
+		if err := s.Append(snap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Trend is a linear fit of health percent over time for one pack
+type Trend struct {
+	Pack          string
+	Latest        Snapshot
+	HealthPercent float64 // Health of the latest snapshot
+	SlopePerYear  float64 // Change in health percentage points per year
+	Samples       int     // Snapshots used for the fit
+	Span          time.Duration
+	TargetPercent float64   // Health level the projection aims for (e.g. 80)
+	ReachesTarget time.Time // Projected date health falls to TargetPercent; zero if unknown
+	OK            bool      // False when history is too short to fit a trend
+}
+
+// minTrendSpan is the least history needed before a degradation rate is fitted
+const minTrendSpan = 7 * 24 * time.Hour
+
+// Packs returns the distinct pack names in the history, sorted
+func Packs(snaps []Snapshot) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, s := range snaps {
+		if !seen[s.Pack] {
+			seen[s.Pack] = true
+			names = append(names, s.Pack)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FitTrend fits health percent against time with ordinary least squares and
+// projects when the pack reaches targetPercent.
+func FitTrend(snaps []Snapshot, pack string, targetPercent float64) Trend {
+	tr := Trend{Pack: pack, TargetPercent: targetPercent, HealthPercent: math.NaN(), SlopePerYear: math.NaN()}
+
+	var pts []Snapshot
+	for _, s := range snaps {
+		if s.Pack == pack && !math.IsNaN(s.Percent()) {
+			pts = append(pts, s)
+		}
+	}
+	if len(pts) == 0 {
+		return tr
+	}
+	sort.Slice(pts, func(i, j int) bool { return pts[i].T.Before(pts[j].T) })
+
+	tr.Latest = pts[len(pts)-1]
+	tr.HealthPercent = tr.Latest.Percent()
+	tr.Samples = len(pts)
+	tr.Span = tr.Latest.T.Sub(pts[0].T)
+	if len(pts) < 2 || tr.Span < minTrendSpan {
+		return tr
+	}
+
+	// x in days since first snapshot, y in health percent
+	t0 := pts[0].T
+	var sumX, sumY, sumXX, sumXY float64
+	for _, p := range pts {
+		x := p.T.Sub(t0).Hours() / 24
+		y := p.Percent()
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	n := float64(len(pts))
+	den := n*sumXX - sumX*sumX
+	if den == 0 {
+		return tr
+	}
+	slope := (n*sumXY - sumX*sumY) / den // percent per day
+	intercept := (sumY - slope*sumX) / n
+
+	tr.SlopePerYear = slope * 365
+	tr.OK = true
+
+	if slope < 0 {
+		days := (targetPercent - intercept) / slope
+		tr.ReachesTarget = t0.Add(time.Duration(days * 24 * float64(time.Hour)))
+	}
+	return tr
+}
+
+// Summary formats a trend as a single human-readable line
+func (tr Trend) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %.1f%% health (%.1f / %.1f Wh", tr.Pack, tr.HealthPercent, tr.Latest.EnergyFull, tr.Latest.EnergyFullDesign)
+	if tr.Latest.CycleCount >= 0 {
+		fmt.Fprintf(&b, ", %d cycles", tr.Latest.CycleCount)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// Projection describes the fitted degradation rate and the projected date
+func (tr Trend) Projection() string {
+	if !tr.OK {
+		return fmt.Sprintf("not enough history for a trend (%d snapshots over %.1f days, need %.0f)",
+			tr.Samples, tr.Span.Hours()/24, minTrendSpan.Hours()/24)
+	}
+	if tr.ReachesTarget.IsZero() {
+		return fmt.Sprintf("%+.2f%%/year, no degradation towards %.0f%%", tr.SlopePerYear, tr.TargetPercent)
+	}
+	if tr.HealthPercent <= tr.TargetPercent {
+		return fmt.Sprintf("%+.2f%%/year, already below %.0f%%", tr.SlopePerYear, tr.TargetPercent)
+	}
+	return fmt.Sprintf("%+.2f%%/year, reaches %.0f%% around %s", tr.SlopePerYear, tr.TargetPercent, tr.ReachesTarget.Format("Jan 2006"))
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// series returns one snapshot of pack per day with full capacity from wh
+func series(pack string, wh ...float64) []Snapshot {
+	out := make([]Snapshot, len(wh))
+	for i, w := range wh {
+		out[i] = Snapshot{T: t0.AddDate(0, 0, i), Pack: pack, EnergyFull: w, EnergyFullDesign: 50, CycleCount: i}
+	}
+	return out
+}
+
+// linear returns days+1 daily snapshots whose health changes by perDay
+// percentage points a day from 100 %
+func linear(days int, perDay float64) []Snapshot {
+	wh := make([]float64, days+1)
+	for i := range wh {
+		wh[i] = 50 * (100 + perDay*float64(i)) / 100
+	}
+	return series("BAT0", wh...)
+}
+
+func TestFitTrend(t *testing.T) {
+	cases := []struct {
+		name    string
+		snaps   []Snapshot
+		ok      bool
+		health  float64
+		slope   float64   // %/year, NaN if not fitted
+		reaches time.Time // Zero if no projection
+		proj    string    // Prefix of Projection()
+	}{
+		{"no history", nil, false, math.NaN(), math.NaN(), time.Time{}, "not enough history for a trend (0 snapshots"},
+		{"one point", series("BAT0", 45), false, 90, math.NaN(), time.Time{}, "not enough history for a trend (1 snapshots"},
+		{"under a week", series("BAT0", 45, 44.9, 44.8), false, 89.6, math.NaN(), time.Time{}, "not enough history for a trend (3 snapshots over 2.0 days"},
+		{"flat", linear(10, 0), true, 100, 0, time.Time{}, "+0.00%/year, no degradation towards 80%"},
+		{"rising", linear(10, 0.1), true, 101, 36.5, time.Time{}, "+36.50%/year, no degradation"},
+		{"falling", linear(10, -0.1), true, 99, -36.5, t0.AddDate(0, 0, 200), "-36.50%/year, reaches 80% around Jul 2026"},
+		{"already below", linear(10, -2), true, 80, -730, t0.AddDate(0, 0, 10), "-730.00%/year, already below 80%"},
+		{"other pack only", series("BAT1", 45, 44), false, math.NaN(), math.NaN(), time.Time{}, "not enough history for a trend (0 snapshots"},
+	}
+	for _, c := range cases {
+		tr := FitTrend(c.snaps, "BAT0", 80)
+		if tr.OK != c.ok || !near(tr.HealthPercent, c.health) || !near(tr.SlopePerYear, c.slope) {
+			t.Errorf("%s: FitTrend() = ok %v, health %v, slope %v", c.name, tr.OK, tr.HealthPercent, tr.SlopePerYear)
+		}
+		if d := tr.ReachesTarget.Sub(c.reaches); d < -time.Minute || d > time.Minute {
+			t.Errorf("%s: ReachesTarget = %v, want %v", c.name, tr.ReachesTarget, c.reaches)
+		}
+		if got := tr.Projection(); !strings.HasPrefix(got, c.proj) {
+			t.Errorf("%s: Projection() = %q", c.name, got)
+		}
+	}
+}
+
+func TestFitTrendSkipsUnknownDesign(t *testing.T) {
+	snaps := linear(10, -0.1)
+	snaps = append(snaps, Snapshot{T: t0.AddDate(0, 0, 11), Pack: "BAT0", EnergyFull: 10})
+	tr := FitTrend(snaps, "BAT0", 80)
+	if tr.Samples != 11 || !near(tr.HealthPercent, 99) {
+		t.Errorf("FitTrend() = %d samples, %v%%", tr.Samples, tr.HealthPercent)
+	}
+}
+
+func near(got, want float64) bool {
+	if math.IsNaN(want) {
+		return math.IsNaN(got)
+	}
+	return math.Abs(got-want) < 1e-6
+}
+
+func TestRecord(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "health.csv")}
+	snap := Snapshot{T: t0, Pack: "BAT0", EnergyFull: 45, EnergyFullDesign: 50, CycleCount: -1}
+	steps := []struct {
+		name   string
+		snaps  []Snapshot
+		logged int
+	}{
+		{"first snapshot", []Snapshot{snap}, 1},
+		{"unchanged", []Snapshot{with(snap, time.Hour, 45, -1)}, 1},
+		{"drift of 0.5%", []Snapshot{with(snap, 2*time.Hour, 44.75, -1)}, 1},
+		{"drift over 0.5%", []Snapshot{with(snap, 3*time.Hour, 44.7, -1)}, 2},
+		{"new cycle count", []Snapshot{with(snap, 4*time.Hour, 44.7, 12)}, 3},
+		{"a day later", []Snapshot{with(snap, 28*time.Hour, 44.7, 12)}, 4},
+		{"no capacity", []Snapshot{with(snap, 29*time.Hour, 0, 13), {T: t0, Pack: "BAT1", EnergyFull: 40}}, 4},
+		{"second pack", []Snapshot{{T: t0, Pack: "BAT1", EnergyFull: 40, EnergyFullDesign: 45, CycleCount: 3}}, 5},
+	}
+	for _, st := range steps {
+		if err := s.Record(st.snaps); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != st.logged {
+			t.Fatalf("%s: %d snapshots logged, want %d", st.name, len(got), st.logged)
+		}
+	}
+
+	got, _ := s.Load()
+	if got[0] != snap || got[2].CycleCount != 12 || got[4].Pack != "BAT1" {
+		t.Errorf("Load() = %+v", got)
+	}
+	if packs := Packs(got); len(packs) != 2 || packs[0] != "BAT0" || packs[1] != "BAT1" {
+		t.Errorf("Packs() = %q", packs)
+	}
+}
+
+func with(s Snapshot, after time.Duration, wh float64, cycles int) Snapshot {
+	s.T = s.T.Add(after)
+	s.EnergyFull = wh
+	s.CycleCount = cycles
+	return s
+}
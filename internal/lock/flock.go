@@ -0,0 +1,73 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrTimeout is returned when another process keeps holding a lock past
+// the timeout
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Flock is an advisory flock(2) lock on a file. The kernel drops it when
+// the file is closed, including when the holder dies, so it never goes
+// stale. Locks taken through separate Flocks conflict even within one
+// process.
+type Flock struct {
+	f *os.File
+}
+
+// Exclusive locks path for writing, waiting up to timeout while other
+// processes hold it. The file is created if needed.
+func Exclusive(path string, timeout time.Duration) (*Flock, error) {
+	return acquire(path, syscall.LOCK_EX, timeout)
+}
+
+// Shared locks path for reading: any number of readers can hold it at
+// once, but not alongside an exclusive holder
+func Shared(path string, timeout time.Duration) (*Flock, error) {
+	return acquire(path, syscall.LOCK_SH, timeout)
+}
+
+func acquire(path string, how int, timeout time.Duration) (*Flock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if errors.Is(err, fs.ErrPermission) && how == syscall.LOCK_SH {
+		// flock needs no write access; a reader may not have it
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	wait := 10 * time.Millisecond
+	for {
+		err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			return &Flock{f: f}, nil
+		}
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, &fs.PathError{Op: "flock", Path: path, Err: err}
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, ErrTimeout)
+		}
+		time.Sleep(min(wait, left))
+		wait = min(wait*2, 250*time.Millisecond)
+	}
+}
+
+// Release drops the lock
+func (l *Flock) Release() error {
+	return l.f.Close()
+}
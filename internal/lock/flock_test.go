@@ -0,0 +1,64 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.csv.lock")
+	r1, err := Shared(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := Shared(path, 0)
+	if err != nil {
+		t.Fatalf("second reader: %v", err)
+	}
+	if _, err := Exclusive(path, 20*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Exclusive() with readers = %v, want ErrTimeout", err)
+	}
+	r1.Release()
+
+	// The writer gets in once the last reader lets go
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		r2.Release()
+	}()
+	w, err := Exclusive(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Shared(path, 0); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Shared() with a writer = %v, want ErrTimeout", err)
+	}
+	w.Release()
+}
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".battery-zen.pid")
+	// A PID left by a daemon that died without releasing does not count
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first := &PIDFile{Path: path}
+	if ok, err := first.Acquire(); !ok || err != nil {
+		t.Fatalf("Acquire() = %v, %v", ok, err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("pid file = %q", b)
+	}
+	second := &PIDFile{Path: path}
+	if ok, err := second.Acquire(); ok || err != nil {
+		t.Fatalf("second Acquire() = %v, %v", ok, err)
+	}
+	first.Release()
+	if ok, err := second.Acquire(); !ok || err != nil {
+		t.Fatalf("Acquire() after Release() = %v, %v", ok, err)
+	}
+	second.Release()
+}
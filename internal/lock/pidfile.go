@@ -1,82 +1,54 @@
 package lock
 
 import (
-	"fmt"
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 )
 
+// PIDFile keeps a second daemon from starting by holding an exclusive
+// flock on the file for as long as the daemon runs. The file also records
+// the holder's PID for people looking, but only the lock counts: the
+// kernel drops it when the process exits, however it exits, so a leftover
+// file never blocks the next start.
 type PIDFile struct {
 	Path string
+	lock *Flock
 }
 
-// isBatteryZenProcess checks if the given PID belongs to a battery-zen process
-func isBatteryZenProcess(pid int) bool {
-	// Check if the process exists
-	procPath := fmt.Sprintf("/proc/%d", pid)
-	if _, err := os.Stat(procPath); err != nil {
-		return false
-	}
-
-	// Read the process command name
-	commPath := fmt.Sprintf("/proc/%d/comm", pid)
-	comm, err := os.ReadFile(commPath)
-	if err != nil {
-		return false
-	}
-
-	// Check if it's battery-zen (trim newline)
-	processName := strings.TrimSpace(string(comm))
-	if processName == "battery-zen" {
-		return true
-	}
-
-	// Also check cmdline as a fallback (in case the process name is truncated)
-	cmdlinePath := fmt.Sprintf("/proc/%d/cmdline", pid)
-	cmdline, err := os.ReadFile(cmdlinePath)
-	if err != nil {
-		return false
-	}
-
-	// cmdline is null-separated, so convert nulls to spaces and check
-	cmdlineStr := string(cmdline)
-	cmdlineStr = strings.ReplaceAll(cmdlineStr, "\x00", " ")
-	return strings.Contains(cmdlineStr, "battery-zen")
-}
-
+// Acquire takes the lock without waiting and reports whether it was free
 func (p *PIDFile) Acquire() (bool, error) {
 	if err := os.MkdirAll(filepath.Dir(p.Path), 0o755); err != nil {
 		return false, err
 	}
-	// Try O_EXCL
-	f, err := os.OpenFile(p.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
-	if err == nil {
-		defer f.Close()
-		_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
-		return true, nil
+	l, err := Exclusive(p.Path, 0)
+	if errors.Is(err, ErrTimeout) {
+		return false, nil
 	}
-	// If exists, check if process is alive and is actually battery-zen; if not, remove and retry
-	b, readErr := os.ReadFile(p.Path)
-	if readErr != nil {
-		return false, readErr
+	if err != nil {
+		return false, err
 	}
-	pid, _ := strconv.Atoi(string(b))
-	if pid > 0 {
-		if isBatteryZenProcess(pid) {
-			// Another battery-zen instance is actually running
-			return false, nil
-		}
+	if err := l.f.Truncate(0); err != nil {
+		l.Release()
+		return false, err
 	}
-	_ = os.Remove(p.Path)
-	f, err = os.OpenFile(p.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
-	if err != nil {
+	if _, err := l.f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		l.Release()
 		return false, err
 	}
-	defer f.Close()
-	_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
+	p.lock = l
 	return true, nil
 }
 
-func (p *PIDFile) Release() { _ = os.Remove(p.Path) }
+// Release clears the PID and drops the lock. The file stays: removing it
+// would let a starting daemon lock a new file while another still holds
+// the old one.
+func (p *PIDFile) Release() {
+	if p.lock == nil {
+		return
+	}
+	_ = p.lock.f.Truncate(0)
+	_ = p.lock.Release()
+	p.lock = nil
+}
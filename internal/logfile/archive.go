@@ -0,0 +1,373 @@
+package logfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveMonth is the layout of the month in archive names
+const archiveMonth = "2006-01"
+
+// ParseTimestamp parses a logged timestamp: RFC 3339, or the formats of
+// hand-made and very old logs
+func ParseTimestamp(tsStr string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, tsStr)
+	if err == nil {
+		return t, nil
+	}
+
+	layouts := []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02T15:04:05",
+	}
+	for _, lay := range layouts {
+		if tt, e2 := time.Parse(lay, tsStr); e2 == nil {
+			return tt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse timestamp")
+}
+
+// ArchivePath returns the monthly archive of the log at path, e.g.
+// logs-2026-09.csv.gz next to logs.csv
+func ArchivePath(path string, month time.Time) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s.gz", strings.TrimSuffix(path, ext), month.Format(archiveMonth), ext)
+}
+
+// Archive is one month of rotated rows
+type Archive struct {
+	Path  string
+	Month time.Time // First day of the month, UTC
+}
+
+// Archives returns the archives of the log at path, oldest first
+func Archives(path string) ([]Archive, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	var out []Archive
+	for _, m := range matches {
+		month, err := time.Parse(archiveMonth, strings.TrimSuffix(strings.TrimPrefix(m, prefix), ext+".gz"))
+		if err != nil {
+			continue
+		}
+		out = append(out, Archive{Path: m, Month: month})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Month.Before(out[j].Month) })
+	return out, nil
+}
+
+// Rotate keeps the header and the last maxDataLines rows of the log, like
+// TrimToLast, but moves the older rows into monthly gzip archives instead
+// of discarding them. Each rotation appends a gzip member holding the log's
+// header block and the rows of that month, so archives stay readable after
+// the schema changes. Archives are written before the log is replaced: a
+// crash in between leaves rows in both rather than losing them, and the
+// next rotation archives them again. ReadRange returns such rows once.
+func (w *Writer) Rotate(maxDataLines int) error {
+	l, err := lockLog(w.Path, true, w.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock(l)
+	if _, err := w.truncateTorn(); err != nil {
+		return err
+	}
+	src, err := os.Open(w.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	br := bufio.NewReader(src)
+	_, headerLen, err := readSchema(br)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, headerLen)
+	if _, err := src.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	lines, err := w.lineCount()
+	if err != nil {
+		return err
+	}
+	drop := lines - strings.Count(string(header), "\n") - maxDataLines
+	if drop <= 0 {
+		return nil
+	}
+
+	// Group the dropped rows by month, keeping file order within each
+	byMonth := make(map[string][]string)
+	var months []string
+	month := time.Now().Format(archiveMonth)
+	for i := 0; i < drop; i++ {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		ts, _, _ := strings.Cut(line, ",")
+		if t, err := ParseTimestamp(strings.TrimSpace(ts)); err == nil {
+			month = t.Format(archiveMonth)
+		}
+		if _, ok := byMonth[month]; !ok {
+			months = append(months, month)
+		}
+		byMonth[month] = append(byMonth[month], line)
+	}
+	for _, m := range months {
+		t, _ := time.Parse(archiveMonth, m)
+		if err := appendArchive(ArchivePath(w.Path, t), header, byMonth[m]); err != nil {
+			return err
+		}
+	}
+
+	return w.replace(func(bw *bufio.Writer) error {
+		if _, err := bw.Write(header); err != nil {
+			return err
+		}
+		_, err := io.Copy(bw, br)
+		return err
+	})
+}
+
+// appendArchive adds one gzip member with the header block and rows. The
+// archive is rewritten through a temp file, so a crash leaves either the
+// old archive or the new one whole; a torn member left by an older version
+// is dropped on the way.
+func appendArchive(path string, header []byte, rows []string) error {
+	return (&Writer{Path: path}).replace(func(bw *bufio.Writer) error {
+		if err := copyMembers(bw, path); err != nil {
+			return err
+		}
+		zw := gzip.NewWriter(bw)
+		if _, err := zw.Write(header); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if _, err := io.WriteString(zw, row); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	})
+}
+
+// copyMembers copies the whole gzip members of the archive at path to w.
+// A missing archive copies nothing.
+func copyMembers(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	n, err := readMembers(f, func([]byte) error { return nil })
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, f, n)
+	return err
+}
+
+// countingReader tracks how far gzip has read. It is a flate.Reader, so
+// gzip reads through it byte by byte without buffering ahead.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// readMembers decodes the gzip members of r in turn, passing each whole
+// member to emit, and returns the offset just past the last one. A member
+// that is cut short or damaged, as a crash in the middle of appending one
+// leaves, ends the walk without an error.
+func readMembers(r io.Reader, emit func([]byte) error) (int64, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+	var end int64
+	zr := new(gzip.Reader)
+	for {
+		if _, err := cr.r.Peek(1); errors.Is(err, io.EOF) {
+			return end, nil
+		} else if err != nil {
+			return end, err
+		}
+		err := zr.Reset(cr)
+		var b []byte
+		if err == nil {
+			zr.Multistream(false)
+			b, err = io.ReadAll(zr)
+		}
+		if err != nil {
+			if damagedMember(err) {
+				return end, nil
+			}
+			return end, err
+		}
+		end = cr.n
+		if err := emit(b); err != nil {
+			return end, err
+		}
+	}
+}
+
+// damagedMember reports whether a gzip error comes from the data rather
+// than from reading the file
+func damagedMember(err error) bool {
+	var corrupt flate.CorruptInputError
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) || errors.As(err, &corrupt)
+}
+
+// Reader reads the sample log together with its monthly archives
+type Reader struct {
+	Path        string
+	LockTimeout time.Duration // Wait for the log's lock, DefaultLockTimeout if zero
+}
+
+// ReadRange returns the rows logged from from up to (not including) to,
+// oldest first, as CSV records under HeaderNames: the first record is the
+// header, and rows from any schema are mapped onto it. A zero from or to
+// leaves that end open. Rows whose timestamp does not parse are kept, and a
+// row archived more than once or still in the log after it was archived is
+// returned once. Only archives of months overlapping the range are opened;
+// a missing log with no archives yields os.ErrNotExist.
+func (r *Reader) ReadRange(from, to time.Time) ([][]string, error) {
+	l, err := lockLog(r.Path, false, r.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock(l)
+	archives, err := Archives(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	out := [][]string{HeaderNames()}
+	archived := make(map[string]bool)
+	inArchive := true
+	keep := func(rec []string) {
+		t, err := ParseTimestamp(strings.TrimSpace(rec[0]))
+		if err == nil && (!from.IsZero() && t.Before(from) || !to.IsZero() && !t.Before(to)) {
+			return
+		}
+		// An interrupted Rotate leaves copies of whole rows, so the same
+		// timestamp with different values is a real row
+		key := strings.Join(rec, ",")
+		if archived[key] {
+			return
+		}
+		if inArchive {
+			archived[key] = true
+		}
+		out = append(out, rec)
+	}
+
+	// Rows are archived by their local month; a day of slack covers offsets
+	for _, a := range archives {
+		if !from.IsZero() && a.Month.AddDate(0, 1, 1).Before(from) || !to.IsZero() && a.Month.AddDate(0, 0, -1).After(to) {
+			continue
+		}
+		if err := readArchive(a.Path, keep); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(a.Path), err)
+		}
+	}
+
+	inArchive = false
+	f, err := os.Open(r.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && len(archives) > 0 {
+			return out, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	if err := readRows(f, keep); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func readArchive(path string, emit func([]string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = readMembers(f, func(b []byte) error {
+		return readRows(bytes.NewReader(b), emit)
+	})
+	return err
+}
+
+// readRows parses log data that may hold several header blocks, passing
+// each row to emit mapped onto HeaderNames. Rows before any header are
+// skipped.
+func readRows(r io.Reader, emit func([]string)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#' // Schema markers
+	var src []int
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ColumnIndex(rec, ColTimestamp) >= 0 && ColumnIndex(rec, ColBattery) >= 0 {
+			src = make([]int, len(Columns))
+			for j, c := range Columns {
+				src[j] = c.Index(rec)
+			}
+			continue
+		}
+		if src == nil {
+			continue
+		}
+		row := make([]string, len(Columns))
+		for j, i := range src {
+			if i >= 0 && i < len(rec) {
+				row[j] = rec[i]
+			}
+		}
+		emit(row)
+	}
+}
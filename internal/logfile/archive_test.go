@@ -0,0 +1,185 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchivePath(t *testing.T) {
+	month := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	if got := ArchivePath("/state/logs.csv", month); got != "/state/logs-2026-09.csv.gz" {
+		t.Errorf("ArchivePath() = %q", got)
+	}
+}
+
+func TestRotateAndReadRange(t *testing.T) {
+	// An old-schema log spanning two months
+	w := writeLog(t, "timestamp,ac_connected,battery_life\n"+
+		"2026-08-31T23:58:00Z,0,90\n"+
+		"2026-08-31T23:59:00Z,0,89\n"+
+		"2026-09-01T00:00:00Z,0,88\n")
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := Archives(w.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 1 || filepath.Base(archives[0].Path) != "logs-2026-08.csv.gz" {
+		t.Fatalf("Archives() = %+v", archives)
+	}
+
+	// Later rows, written after the header was upgraded
+	if err := w.AppendCSV(record("2026-09-01T00:01:00Z", 87)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AppendCSV(record("2026-09-01T00:02:00Z", 86)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(t, w); len(lines) != 3 {
+		t.Errorf("live file = %q", lines)
+	}
+
+	r := &Reader{Path: w.Path}
+	all, err := r.ReadRange(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rec := range all[1:] {
+		got = append(got, rec[0]+"="+rec[2])
+		if len(rec) != len(Columns) {
+			t.Errorf("record %q not mapped onto the registry", rec)
+		}
+	}
+	want := []string{
+		"2026-08-31T23:58:00Z=90", "2026-08-31T23:59:00Z=89", "2026-09-01T00:00:00Z=88",
+		"2026-09-01T00:01:00Z=87", "2026-09-01T00:02:00Z=86",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ReadRange() rows = %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	some, err := r.ReadRange(time.Date(2026, 8, 31, 23, 59, 0, 0, time.UTC), time.Date(2026, 9, 1, 0, 2, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(some) != 4 || some[1][0] != "2026-08-31T23:59:00Z" || some[3][0] != "2026-09-01T00:01:00Z" {
+		t.Errorf("bounded ReadRange() = %q", some)
+	}
+
+	// Only the archives remain
+	if err := os.Remove(w.Path); err != nil {
+		t.Fatal(err)
+	}
+	if rest, err := r.ReadRange(time.Time{}, time.Time{}); err != nil || len(rest) != 5 {
+		t.Errorf("ReadRange() without live file = %d records, %v", len(rest), err)
+	}
+}
+
+func TestReadRangeAfterInterruptedRotate(t *testing.T) {
+	header := "timestamp,ac_connected,battery_life\n"
+	rows := []string{
+		"2026-09-01T00:00:00Z,0,90\n",
+		"2026-09-01T00:01:00Z,0,89\n",
+		"2026-09-01T00:01:00Z,1,89\n", // Plugged in within the same second
+		"2026-09-01T00:02:00Z,1,90\n",
+	}
+	w := writeLog(t, header+strings.Join(rows, ""))
+	// Rotate(1) archived three rows and crashed before replacing the log
+	archive := ArchivePath(w.Path, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	if err := appendArchive(archive, []byte(header), rows[:3]); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reader{Path: w.Path}
+	check := func(what string) {
+		t.Helper()
+		all, err := r.ReadRange(time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, rec := range all[1:] {
+			got = append(got, strings.Join(rec[:3], ","))
+		}
+		want := []string{"2026-09-01T00:00:00Z,0,90", "2026-09-01T00:01:00Z,0,89", "2026-09-01T00:01:00Z,1,89", "2026-09-01T00:02:00Z,1,90"}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: ReadRange() = %q", what, got)
+		}
+	}
+	check("archive and log overlap")
+
+	// The next rotation archives the same rows again
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	check("rows archived twice")
+}
+
+func TestArchiveTornMember(t *testing.T) {
+	w := writeLog(t, "timestamp,ac_connected,battery_life\n"+
+		"2026-09-01T00:00:00Z,0,90\n"+
+		"2026-09-01T00:01:00Z,0,89\n")
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	archive := ArchivePath(w.Path, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	whole, err := os.Stat(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AppendCSV(record("2026-09-01T00:02:00Z", 88)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	// A crash cut the second member short
+	if err := os.Truncate(archive, whole.Size()+10); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reader{Path: w.Path}
+	timestamps := func() string {
+		t.Helper()
+		all, err := r.ReadRange(time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("ReadRange() = %v", err)
+		}
+		var ts []string
+		for _, rec := range all[1:] {
+			ts = append(ts, rec[0][14:16])
+		}
+		return strings.Join(ts, " ")
+	}
+	// The row in the torn member is lost, the rest still reads
+	if got := timestamps(); got != "00 02" {
+		t.Errorf("minutes read = %q, want 00 02", got)
+	}
+
+	// The next rotation drops the torn member rather than burying it
+	if err := w.AppendCSV(record("2026-09-01T00:03:00Z", 87)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := timestamps(); got != "00 02 03" {
+		t.Errorf("minutes read after rotating = %q, want 00 02 03", got)
+	}
+	if _, err := os.Stat(archive + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp archive left: %v", err)
+	}
+}
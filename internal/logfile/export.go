@@ -0,0 +1,95 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// importBatch bounds how many rows Import hands to the store at once
+const importBatch = 4096
+
+// Export writes the rows of s from from up to to as a CSV log in the
+// current schema, marker and header included
+func Export(w io.Writer, s Store, from, to time.Time) (int, error) {
+	rows, err := s.Range(from, to)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fmt.Fprintf(w, "%s%d\n", schemaPrefix, SchemaVersion); err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return 0, err
+	}
+	return len(rows) - 1, nil
+}
+
+// ReadCSVFile reads a CSV log or a gzip archive of one (.gz) of any schema,
+// mapped onto HeaderNames with the header as the first record
+func ReadCSVFile(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	out := [][]string{HeaderNames()}
+	if err := readRows(r, func(row []string) { out = append(out, row) }); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Import appends the rows of a CSV log or archive to s in time order and
+// returns how many were imported and how many were skipped for lacking a
+// readable timestamp. Importing into a store that already holds later rows
+// leaves them out of order.
+func Import(path string, s Store) (int, int, error) {
+	recs, err := ReadCSVFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	type timed struct {
+		t   time.Time
+		row []string
+	}
+	var rows []timed
+	skipped := 0
+	for _, rec := range recs[1:] {
+		t, err := ParseTimestamp(strings.TrimSpace(rec[0]))
+		if err != nil {
+			skipped++
+			continue
+		}
+		rows = append(rows, timed{t, rec})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].t.Before(rows[j].t) })
+
+	batch := make([][]string, 0, importBatch)
+	for i, r := range rows {
+		batch = append(batch, r.row)
+		if len(batch) == importBatch || i == len(rows)-1 {
+			if err := s.AppendRows(batch); err != nil {
+				return i + 1 - len(batch), skipped, err
+			}
+			batch = batch[:0]
+		}
+	}
+	return len(rows), skipped, nil
+}
@@ -0,0 +1,45 @@
+package logfile
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/lock"
+)
+
+// DefaultLockTimeout is how long a log operation waits for another process
+// holding the log's lock when no timeout is set
+const DefaultLockTimeout = 10 * time.Second
+
+// LockPath returns the file whose flock guards the log or segment
+// directory at path, e.g. logs.csv.lock. Appends, trims, rotations and
+// migrations hold it exclusively; reads hold it shared, so they never see
+// a log between an archive write and the replace that follows it.
+func LockPath(path string) string {
+	return path + ".lock"
+}
+
+// lockLog locks the log at path, exclusively for changes and shared for
+// reads. When its directory does not exist there is no log to guard and a
+// nil lock is returned.
+func lockLog(path string, exclusive bool, timeout time.Duration) (*lock.Flock, error) {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	acquire := lock.Shared
+	if exclusive {
+		acquire = lock.Exclusive
+	}
+	l, err := acquire(LockPath(path), timeout)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return l, err
+}
+
+func unlock(l *lock.Flock) {
+	if l != nil {
+		l.Release()
+	}
+}
@@ -0,0 +1,53 @@
+package logfile
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/lock"
+)
+
+func TestLogLocking(t *testing.T) {
+	w := writeLog(t, "timestamp,ac_connected,battery_life\n"+
+		"2026-09-01T00:00:00Z,0,90\n"+
+		"2026-09-01T00:01:00Z,0,89\n")
+	w.LockTimeout = 20 * time.Millisecond
+
+	// Another process is reading: changes wait and then give up
+	held, err := lock.Shared(LockPath(w.Path), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.TrimToLast(1); !errors.Is(err, lock.ErrTimeout) {
+		t.Fatalf("TrimToLast() under a reader = %v, want ErrTimeout", err)
+	}
+	if err := w.AppendCSV(record("2026-09-01T00:02:00Z", 88)); !errors.Is(err, lock.ErrTimeout) {
+		t.Fatalf("AppendCSV() under a reader = %v, want ErrTimeout", err)
+	}
+	r := &Reader{Path: w.Path, LockTimeout: w.LockTimeout}
+	if rows, err := r.ReadRange(time.Time{}, time.Time{}); err != nil || len(rows) != 3 {
+		t.Fatalf("ReadRange() beside a reader = %d records, %v", len(rows), err)
+	}
+	held.Release()
+
+	// A trim in progress holds readers off until it is done
+	held, err = lock.Exclusive(LockPath(w.Path), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		held.Release()
+	}()
+	r.LockTimeout = time.Second
+	if rows, err := r.ReadRange(time.Time{}, time.Time{}); err != nil || len(rows) != 3 {
+		t.Fatalf("ReadRange() after the writer = %d records, %v", len(rows), err)
+	}
+	if err := w.TrimToLast(1); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(t, w); len(lines) != 2 {
+		t.Errorf("trimmed file = %q", lines)
+	}
+}
@@ -0,0 +1,118 @@
+package logfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Migration describes what Migrate changed in one log
+type Migration struct {
+	From    int      // Schema version before
+	Rows    int      // Data rows carried over
+	Added   []string // Registered columns the log lacked
+	Renamed []string // Header fields renamed to their registered name, as "old→new"
+}
+
+// Changed reports whether the log was rewritten
+func (m Migration) Changed() bool {
+	return m.From < SchemaVersion || len(m.Added) > 0 || len(m.Renamed) > 0
+}
+
+// Migrate rewrites the log at path in the current schema: registered
+// columns in registry order under their current names, missing ones added
+// empty, unknown ones kept at the end. Every data row is carried over,
+// padded or cut to the header. The file is replaced atomically and left
+// untouched when it is already current.
+func (w *Writer) Migrate() (Migration, error) {
+	l, err := lockLog(w.Path, true, w.LockTimeout)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer unlock(l)
+	f, err := os.Open(w.Path)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	s, _, err := readSchema(br)
+	if err != nil {
+		return Migration{}, err
+	}
+	m := Migration{From: s.Version}
+	if len(s.Header) == 0 {
+		return m, errors.New("log has no header")
+	}
+
+	// src[i] is the old index of new column i, -1 for added columns
+	var header []string
+	var src []int
+	used := make([]bool, len(s.Header))
+	for _, c := range Columns {
+		i := c.Index(s.Header)
+		if i < 0 {
+			m.Added = append(m.Added, c.Name)
+		} else {
+			used[i] = true
+			if s.Header[i] != c.Name {
+				m.Renamed = append(m.Renamed, fmt.Sprintf("%s→%s", s.Header[i], c.Name))
+			}
+		}
+		header = append(header, c.Name)
+		src = append(src, i)
+	}
+	for i, h := range s.Header {
+		if !used[i] {
+			header = append(header, h)
+			src = append(src, i)
+		}
+	}
+
+	r := csv.NewReader(br)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	recs, err := r.ReadAll()
+	if err != nil {
+		return m, err
+	}
+	m.Rows = len(recs)
+	if !m.Changed() && inOrder(src) {
+		return m, nil
+	}
+
+	err = w.replace(func(bw *bufio.Writer) error {
+		if _, err := bw.WriteString(headerBlock(header)); err != nil {
+			return err
+		}
+		cw := csv.NewWriter(bw)
+		out := make([]string, len(header))
+		for _, rec := range recs {
+			for j, i := range src {
+				out[j] = ""
+				if i >= 0 && i < len(rec) {
+					out[j] = rec[i]
+				}
+			}
+			if err := cw.Write(out); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	return m, err
+}
+
+// inOrder reports whether no column moves
+func inOrder(src []int) bool {
+	for j, i := range src {
+		if i != j {
+			return false
+		}
+	}
+	return true
+}
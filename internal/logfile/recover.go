@@ -0,0 +1,235 @@
+package logfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Recovery reports what Recover repaired after an unclean shutdown
+type Recovery struct {
+	Torn        int64    // Bytes of a torn final record that were cut off
+	ArchiveTorn int64    // Bytes of torn final gzip members cut off the archives
+	TempFiles   []string // Leftover temp files of an interrupted replace that were removed
+}
+
+// Repaired reports whether anything needed fixing
+func (r Recovery) Repaired() bool {
+	return r.Torn > 0 || r.ArchiveTorn > 0 || len(r.TempFiles) > 0
+}
+
+func (r Recovery) String() string {
+	var parts []string
+	if r.Torn > 0 {
+		parts = append(parts, fmt.Sprintf("cut %d bytes of a torn final record", r.Torn))
+	}
+	if r.ArchiveTorn > 0 {
+		parts = append(parts, fmt.Sprintf("cut %d bytes of torn archive members", r.ArchiveTorn))
+	}
+	if n := len(r.TempFiles); n > 0 {
+		parts = append(parts, fmt.Sprintf("removed %d stale temp file(s)", n))
+	}
+	if len(parts) == 0 {
+		return "nothing to repair"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Recover removes temp files left by an interrupted trim, header upgrade or
+// rotation, cuts a torn final line, as a crash or power loss in the middle
+// of an append leaves, and cuts a torn final member off each archive. Call
+// it on startup, before the first append.
+func (w *Writer) Recover() (Recovery, error) {
+	var rec Recovery
+	l, err := lockLog(w.Path, true, w.LockTimeout)
+	if err != nil {
+		return rec, err
+	}
+	defer unlock(l)
+	ext := filepath.Ext(w.Path)
+	archiveTmps, err := filepath.Glob(strings.TrimSuffix(w.Path, ext) + "-*" + ext + ".gz.tmp")
+	if err != nil {
+		return rec, err
+	}
+	for _, tmp := range append([]string{w.Path + ".tmp"}, archiveTmps...) {
+		if err := os.Remove(tmp); err == nil {
+			rec.TempFiles = append(rec.TempFiles, tmp)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return rec, err
+		}
+	}
+	n, err := w.truncateTorn()
+	rec.Torn = n
+	if err != nil {
+		return rec, err
+	}
+
+	archives, err := Archives(w.Path)
+	if err != nil {
+		return rec, err
+	}
+	for _, a := range archives {
+		n, err := truncateTornMember(a.Path)
+		rec.ArchiveTorn += n
+		if err != nil {
+			return rec, err
+		}
+	}
+	return rec, nil
+}
+
+// truncateTornMember cuts the archive back to the end of its last whole
+// gzip member and returns how many bytes went
+func truncateTornMember(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end, err := readMembers(f, func([]byte) error { return nil })
+	if err != nil || end == info.Size() {
+		return 0, err
+	}
+	if err := f.Truncate(end); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return info.Size() - end, nil
+}
+
+// truncateTorn cuts a final line without a newline and returns how many
+// bytes went. Such a line is usually an append that was never completely
+// written; if it does not parse or has fewer fields than the header, none
+// of it can be trusted. A whole row, as a hand-made log can end in, just
+// gets its newline. If not even the header survives, the file is emptied
+// so the next append starts it afresh.
+func (w *Writer) truncateTorn() (int64, error) {
+	f, err := os.OpenFile(w.Path, os.O_RDWR, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, nil
+	}
+	cut, err := lastLineEnd(f, size)
+	if err != nil || cut == size {
+		return 0, err
+	}
+
+	s, _, err := readSchema(bufio.NewReader(io.NewSectionReader(f, 0, cut)))
+	if err != nil {
+		return 0, err
+	}
+	if len(s.Header) == 0 {
+		cut = 0
+	} else if completeRow(io.NewSectionReader(f, cut, size-cut), len(s.Header)) {
+		if _, err := f.WriteAt([]byte("\n"), size); err != nil {
+			return 0, err
+		}
+		return 0, f.Sync()
+	}
+	if err := f.Truncate(cut); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return size - cut, nil
+}
+
+// completeRow reports whether r holds one CSV row of at least fields fields
+func completeRow(r io.Reader, fields int) bool {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rec, err := cr.Read()
+	return err == nil && len(rec) >= fields
+}
+
+// lastLineEnd returns the offset just past the last newline in the first
+// size bytes of f, or 0 if there is none
+func lastLineEnd(f *os.File, size int64) (int64, error) {
+	const chunk = 4096
+	buf := make([]byte, chunk)
+	for end := size; end > 0; {
+		start := max(end-chunk, 0)
+		b := buf[:end-start]
+		if _, err := f.ReadAt(b, start); err != nil {
+			return 0, err
+		}
+		for i := len(b) - 1; i >= 0; i-- {
+			if b[i] == '\n' {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// Recover removes temp files of a segment creation that did not finish and
+// cuts a torn record off the last segment
+func (s *SegmentStore) Recover() (Recovery, error) {
+	var rec Recovery
+	l, err := lockLog(s.Dir, true, s.LockTimeout)
+	if err != nil {
+		return rec, err
+	}
+	defer unlock(l)
+	tmps, err := filepath.Glob(filepath.Join(s.Dir, "*.tmp"))
+	if err != nil {
+		return rec, err
+	}
+	for _, tmp := range tmps {
+		if err := os.Remove(tmp); err != nil {
+			return rec, err
+		}
+		rec.TempFiles = append(rec.TempFiles, tmp)
+	}
+
+	segs, err := s.segments()
+	if err != nil || len(segs) == 0 {
+		return rec, err
+	}
+	a, err := openAppender(segs[len(segs)-1], true)
+	if err != nil {
+		return rec, err
+	}
+	rec.Torn = a.torn
+	return rec, a.close()
+}
+
+// syncDir flushes a directory so a file created or renamed in it survives a
+// crash. Filesystems that cannot sync directories are taken as they are.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package logfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoverTornLine(t *testing.T) {
+	w := &Writer{Path: filepath.Join(t.TempDir(), "logs.csv"), Sync: true}
+	if err := w.AppendCSV(record("2026-09-01T00:00:00Z", 90)); err != nil {
+		t.Fatal(err)
+	}
+	whole, err := os.ReadFile(w.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AppendCSV(record("2026-09-01T00:01:00Z", 89)); err != nil {
+		t.Fatal(err)
+	}
+	full, err := os.ReadFile(w.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every way the second append can be cut short of its last field
+	last := bytes.LastIndexByte(full, ',')
+	for cut := len(whole) + 1; cut <= last; cut++ {
+		if err := os.WriteFile(w.Path, full[:cut], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(w.Path+".tmp", []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rec, err := w.Recover()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Torn != int64(cut-len(whole)) || len(rec.TempFiles) != 1 {
+			t.Fatalf("cut at %d: Recover() = %+v", cut, rec)
+		}
+		if got, _ := os.ReadFile(w.Path); string(got) != string(whole) {
+			t.Fatalf("cut at %d: file = %q", cut, got)
+		}
+		if _, err := os.Stat(w.Path + ".tmp"); !os.IsNotExist(err) {
+			t.Fatalf("temp file left: %v", err)
+		}
+	}
+
+	if rec, err := w.Recover(); err != nil || rec.Repaired() {
+		t.Errorf("Recover() on a clean log = %+v, %v", rec, err)
+	}
+}
+
+func TestRecoverTornArchive(t *testing.T) {
+	w := writeLog(t, "timestamp,ac_connected,battery_life\n"+
+		"2026-09-01T00:00:00Z,0,90\n"+
+		"2026-09-01T00:01:00Z,0,89\n"+
+		"2026-09-01T00:02:00Z,0,88\n")
+	if err := w.Rotate(1); err != nil {
+		t.Fatal(err)
+	}
+	archive := ArchivePath(w.Path, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	whole, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Power lost in the middle of a second member, and before the rename
+	// of a later rotation
+	if err := os.WriteFile(archive, append(whole, whole[:len(whole)/2]...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive+".tmp", whole[:5], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := w.Recover()
+	if err != nil || rec.ArchiveTorn != int64(len(whole)/2) || len(rec.TempFiles) != 1 || rec.TempFiles[0] != archive+".tmp" {
+		t.Fatalf("Recover() = %+v, %v", rec, err)
+	}
+	if got, _ := os.ReadFile(archive); !bytes.Equal(got, whole) {
+		t.Errorf("archive is %d bytes, want %d", len(got), len(whole))
+	}
+	rows, err := (&Reader{Path: w.Path}).ReadRange(time.Time{}, time.Time{})
+	if err != nil || len(rows) != 4 {
+		t.Errorf("ReadRange() after recovery = %d records, %v", len(rows), err)
+	}
+	if rec, err := w.Recover(); err != nil || rec.Repaired() {
+		t.Errorf("Recover() on a clean archive = %+v, %v", rec, err)
+	}
+}
+
+func TestRecoverRowWithoutNewline(t *testing.T) {
+	// A hand-made log whose last row is whole but has no final newline
+	w := writeLog(t, "timestamp,ac_connected,battery_life\n2026-09-01T00:00:00Z,0,90\n2026-09-01T00:01:00Z,0,89")
+	if rec, err := w.Recover(); err != nil || rec.Repaired() {
+		t.Fatalf("Recover() = %+v, %v", rec, err)
+	}
+	if err := w.AppendCSV(record("2026-09-01T00:02:00Z", 88)); err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, w)
+	// The append upgrades the header, so the marker comes first
+	if len(lines) != 5 || lines[3] != "2026-09-01T00:01:00Z,0,89" || !strings.HasPrefix(lines[4], "2026-09-01T00:02:00Z,0,88,") {
+		t.Errorf("file = %q", lines)
+	}
+
+	// A row that ends in an unclosed quote is still torn
+	w = writeLog(t, "timestamp,ac_connected,batteries\n2026-09-01T00:00:00Z,0,\"BAT0=90")
+	if rec, err := w.Recover(); err != nil || rec.Torn != int64(len("2026-09-01T00:00:00Z,0,\"BAT0=90")) {
+		t.Errorf("Recover() = %+v, %v", rec, err)
+	}
+}
+
+func TestRecoverTornHeader(t *testing.T) {
+	w := writeLog(t, "# battery-zen schema 2\ntimestamp,ac_conn")
+	rec, err := w.Recover()
+	if err != nil || rec.Torn != int64(len("# battery-zen schema 2\ntimestamp,ac_conn")) {
+		t.Fatalf("Recover() = %+v, %v", rec, err)
+	}
+	if err := w.AppendCSV(record("2026-09-01T00:00:00Z", 90)); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(t, w); len(lines) != 3 || lines[1] != strings.Join(HeaderNames(), ",") {
+		t.Errorf("file = %q", lines)
+	}
+}
+
+func TestAppendAfterTornLine(t *testing.T) {
+	// A crash left half a row and nothing called Recover
+	w := writeLog(t, "timestamp,ac_connected,battery_life\n2026-09-01T00:00:00Z,0,90\n2026-09-01T00:0")
+	if err := w.AppendCSV(record("2026-09-01T00:02:00Z", 88)); err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, w)
+	if len(lines) != 4 || !strings.HasPrefix(lines[3], "2026-09-01T00:02:00Z,0,88,") {
+		t.Errorf("file = %q", lines)
+	}
+
+	// Trimming a torn log must not keep the fragment as a row either
+	if err := os.WriteFile(w.Path, []byte(strings.Join(lines, "\n")+"\n2026-09-01T00:03"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.TrimToLast(1); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(t, w); len(lines) != 3 || !strings.HasPrefix(lines[2], "2026-09-01T00:02:00Z,") {
+		t.Errorf("trimmed file = %q", lines)
+	}
+}
+
+func TestSegmentStoreRecover(t *testing.T) {
+	s := &SegmentStore{Dir: filepath.Join(t.TempDir(), "logs.seg"), Sync: true}
+	if err := s.AppendRows(minuteRows(0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	segs, err := s.segments()
+	if err != nil || len(segs) != 1 {
+		t.Fatalf("segments() = %v, %v", segs, err)
+	}
+	info, err := os.Stat(segs[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A torn last record and a segment whose creation never finished
+	if err := os.Truncate(segs[0].path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, "001788221000.seg.tmp"), []byte(segmentMagic), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := s.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Torn == 0 || len(rec.TempFiles) != 1 {
+		t.Errorf("Recover() = %+v", rec)
+	}
+	rows, err := s.Range(time.Time{}, time.Time{})
+	if err != nil || len(rows) != 3 || rows[2][0] != "2026-09-01T00:01:00Z" {
+		t.Errorf("Range() = %d records, %v", len(rows), err)
+	}
+	if rec, err := s.Recover(); err != nil || rec.Repaired() {
+		t.Errorf("second Recover() = %+v, %v", rec, err)
+	}
+}
@@ -0,0 +1,217 @@
+package logfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the layout of the sample log written by AppendCSV. When
+// adding a column, bump it and give the column that Since.
+const SchemaVersion = 2
+
+// Logs written before the schema marker existed count as version 1
+const unversioned = 1
+
+// schemaPrefix starts the marker line written above the CSV header. Readers
+// skip it as a comment.
+const schemaPrefix = "# battery-zen schema "
+
+// Column names of the sample log, shared with analytics.ParseCSVRows
+const (
+	ColTimestamp        = "timestamp"
+	ColAC               = "ac_connected"
+	ColBattery          = "battery_life"
+	ColPacks            = "batteries"
+	ColEnergyNow        = "energy_now_wh"
+	ColEnergyFull       = "energy_full_wh"
+	ColEnergyFullDesign = "energy_full_design_wh"
+	ColPower            = "power_w"
+	ColStatus           = "status"
+	ColCapacityLevel    = "capacity_level"
+	ColEvent            = "event"
+	ColChargeLimit      = "charge_limit"
+	ColCharger          = "charger"
+	ColChargerType      = "charger_type"
+	ColChargerMaxW      = "charger_max_w"
+	ColScreenOn         = "screen_on"
+	ColIdleSecs         = "idle_secs"
+	ColCPUUtil          = "cpu_util_pct"
+	ColLoadAvg          = "load_avg"
+	ColCPUFreq          = "cpu_freq_mhz"
+	ColProfile          = "platform_profile"
+	ColSuspended        = "suspended_secs"
+	ColBootID           = "boot_id"
+	ColUptime           = "uptime_secs"
+	ColLidOpen          = "lid_open"
+	ColExternalDisplays = "external_displays"
+)
+
+// Column is one field of the sample log
+type Column struct {
+	Name    string
+	Aliases []string // Header names of hand-made or very old logs, accepted on read
+	Since   int      // Schema version that added the column
+	format  func(Record) string
+}
+
+// Columns lists the fields of the sample log in the order new files are
+// written. Columns are only ever appended, so older logs stay a prefix.
+var Columns = []Column{
+	{Name: ColTimestamp, Since: 1, format: func(r Record) string { return r.Timestamp }},
+	{Name: ColAC, Aliases: []string{"ac", "ac plugged in (bool)", "ac plugged in"}, Since: 1, format: func(r Record) string {
+		if r.AC {
+			return "1"
+		}
+		return "0"
+	}},
+	{Name: ColBattery, Aliases: []string{"battery", "battery life (%)"}, Since: 1, format: func(r Record) string { return strconv.Itoa(r.Percent) }},
+	{Name: ColPacks, Since: 2, format: func(r Record) string { return FormatPacks(r.Packs) }},
+	{Name: ColEnergyNow, Since: 2, format: func(r Record) string { return formatFloat(r.EnergyNow, 3) }},
+	{Name: ColEnergyFull, Since: 2, format: func(r Record) string { return formatFloat(r.EnergyFull, 3) }},
+	{Name: ColEnergyFullDesign, Since: 2, format: func(r Record) string { return formatFloat(r.EnergyFullDesign, 3) }},
+	{Name: ColPower, Since: 2, format: func(r Record) string { return formatFloat(r.Power, 3) }},
+	{Name: ColStatus, Since: 2, format: func(r Record) string { return r.Status }},
+	{Name: ColCapacityLevel, Since: 2, format: func(r Record) string { return r.CapacityLevel }},
+	{Name: ColEvent, Since: 2, format: func(r Record) string { return strings.ReplaceAll(r.Event, ",", ";") }},
+	{Name: ColChargeLimit, Since: 2, format: func(r Record) string { return formatOptionalInt(r.ChargeLimit) }},
+	{Name: ColCharger, Since: 2, format: func(r Record) string { return r.Charger }},
+	{Name: ColChargerType, Since: 2, format: func(r Record) string { return r.ChargerType }},
+	{Name: ColChargerMaxW, Since: 2, format: func(r Record) string { return formatFloat(r.ChargerMaxW, 1) }},
+	{Name: ColScreenOn, Since: 2, format: func(r Record) string { return formatOptionalInt(r.ScreenOn) }},
+	{Name: ColIdleSecs, Since: 2, format: func(r Record) string { return formatOptionalInt(r.IdleSecs) }},
+	{Name: ColCPUUtil, Since: 2, format: func(r Record) string { return formatFloat(r.CPUUtil, 1) }},
+	{Name: ColLoadAvg, Since: 2, format: func(r Record) string { return formatFloat(r.LoadAvg, 2) }},
+	{Name: ColCPUFreq, Since: 2, format: func(r Record) string { return formatFloat(r.CPUFreqMHz, 0) }},
+	{Name: ColProfile, Since: 2, format: func(r Record) string { return r.PlatformProfile }},
+	{Name: ColSuspended, Since: 2, format: func(r Record) string { return formatFloat(r.SuspendedSecs, 1) }},
+	{Name: ColBootID, Since: 2, format: func(r Record) string { return r.BootID }},
+	{Name: ColUptime, Since: 2, format: func(r Record) string { return formatFloat(r.UptimeSecs, 0) }},
+	{Name: ColLidOpen, Since: 2, format: func(r Record) string { return formatOptionalInt(r.LidOpen) }},
+	{Name: ColExternalDisplays, Since: 2, format: func(r Record) string { return formatOptionalInt(r.ExternalDisplays) }},
+}
+
+// matches reports whether a header field names the column
+func (c Column) matches(field string) bool {
+	field = strings.ToLower(strings.TrimSpace(field))
+	if field == c.Name {
+		return true
+	}
+	for _, a := range c.Aliases {
+		if field == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Index returns the position of the column in header, matching its name or
+// an alias case-insensitively, or -1
+func (c Column) Index(header []string) int {
+	for i, h := range header {
+		if c.matches(h) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ColumnIndex returns the position of the named column in header, or -1.
+// Registered columns also match their aliases.
+func ColumnIndex(header []string, name string) int {
+	for _, c := range Columns {
+		if c.Name == name {
+			return c.Index(header)
+		}
+	}
+	return Column{Name: strings.ToLower(strings.TrimSpace(name))}.Index(header)
+}
+
+// canonical returns the registered name for a header field, or the field
+// itself when it is not a registered column
+func canonical(field string) string {
+	for _, c := range Columns {
+		if c.matches(field) {
+			return c.Name
+		}
+	}
+	return field
+}
+
+// HeaderNames returns the column names written to new logs
+func HeaderNames() []string {
+	names := make([]string, len(Columns))
+	for i, c := range Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Schema is the layout of an existing log file
+type Schema struct {
+	Version int      // From the marker line, 1 for logs without one
+	Header  []string // Column names as written in the file
+}
+
+// Missing returns the registered columns the header lacks
+func (s Schema) Missing() []Column {
+	var out []Column
+	for _, c := range Columns {
+		if c.Index(s.Header) < 0 {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Current reports whether the file already has the layout AppendCSV writes
+func (s Schema) Current() bool {
+	return s.Version >= SchemaVersion && len(s.Missing()) == 0
+}
+
+// ReadSchema returns the marker version and header of the log at path.
+// Files that do not exist yield os.ErrNotExist; empty files an empty header.
+func ReadSchema(path string) (Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Schema{}, err
+	}
+	defer f.Close()
+	s, _, err := readSchema(bufio.NewReader(f))
+	return s, err
+}
+
+// readSchema consumes the marker and header lines from r and returns the
+// schema and the number of bytes read
+func readSchema(r *bufio.Reader) (Schema, int64, error) {
+	s := Schema{Version: unversioned}
+	var n int64
+	for {
+		line, err := r.ReadString('\n')
+		n += int64(len(line))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return s, n, err
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		if v, ok := strings.CutPrefix(trimmed, schemaPrefix); ok {
+			if ver, perr := strconv.Atoi(strings.TrimSpace(v)); perr == nil {
+				s.Version = ver
+			}
+		} else if trimmed != "" {
+			s.Header = strings.Split(trimmed, ",")
+			return s, n, nil
+		}
+		if err != nil {
+			return s, n, nil
+		}
+	}
+}
+
+// headerBlock renders the marker and header lines for a file
+func headerBlock(header []string) string {
+	return fmt.Sprintf("%s%d\n%s\n", schemaPrefix, SchemaVersion, strings.Join(header, ","))
+}
@@ -0,0 +1,131 @@
+package logfile
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLog(t *testing.T, content string) *Writer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "logs.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &Writer{Path: path}
+}
+
+func readLines(t *testing.T, w *Writer) []string {
+	t.Helper()
+	b, err := os.ReadFile(w.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func record(ts string, percent int) Record {
+	return Record{
+		Timestamp: ts, Percent: percent, ChargeLimit: -1, ScreenOn: 1, IdleSecs: -1,
+		EnergyNow: math.NaN(), EnergyFull: math.NaN(), EnergyFullDesign: math.NaN(), Power: 7.5,
+		ChargerMaxW: math.NaN(), CPUUtil: math.NaN(), LoadAvg: math.NaN(), CPUFreqMHz: math.NaN(),
+		SuspendedSecs: math.NaN(), UptimeSecs: math.NaN(), LidOpen: -1, ExternalDisplays: -1,
+	}
+}
+
+func TestAppendCSVNewFile(t *testing.T) {
+	w := &Writer{Path: filepath.Join(t.TempDir(), "logs.csv")}
+	if err := w.AppendCSV(record("2024-01-01T10:00:00Z", 80)); err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, w)
+	if len(lines) != 3 || lines[0] != "# battery-zen schema 2" || lines[1] != strings.Join(HeaderNames(), ",") {
+		t.Fatalf("file = %q", lines)
+	}
+	if f := strings.Split(lines[2], ","); len(f) != len(Columns) || f[2] != "80" || f[7] != "7.500" {
+		t.Errorf("row = %q", lines[2])
+	}
+	s, err := ReadSchema(w.Path)
+	if err != nil || !s.Current() {
+		t.Errorf("ReadSchema() = %+v, %v", s, err)
+	}
+}
+
+func TestAppendCSVUpgradesHeader(t *testing.T) {
+	w := writeLog(t, "timestamp,ac_connected,battery_life,power_w\n2024-01-01T10:00:00Z,0,81,8.000\n")
+	if err := w.AppendCSV(record("2024-01-01T10:01:00Z", 80)); err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, w)
+	if len(lines) != 4 || lines[0] != "# battery-zen schema 2" {
+		t.Fatalf("file = %q", lines)
+	}
+	header := strings.Split(lines[1], ",")
+	if len(header) != len(Columns) || header[3] != ColPower || header[4] != ColPacks {
+		t.Errorf("header = %q", header)
+	}
+	if lines[2] != "2024-01-01T10:00:00Z,0,81,8.000" {
+		t.Errorf("old row changed: %q", lines[2])
+	}
+	// New rows follow the file's column order, not the registry's
+	if f := strings.Split(lines[3], ","); f[3] != "7.500" || len(f) != len(header) {
+		t.Errorf("new row = %q", lines[3])
+	}
+	if _, err := os.Stat(w.Path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temp file left behind")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	w := writeLog(t, "Timestamp,AC plugged in (bool),Battery life (%),note\n"+
+		"2024-01-01 10:00:00,true,81,a\n"+
+		"2024-01-01 10:01:00,true\n")
+	m, err := w.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.From != 1 || m.Rows != 2 || len(m.Renamed) != 3 || len(m.Added) != len(Columns)-3 {
+		t.Errorf("Migrate() = %+v", m)
+	}
+	lines := readLines(t, w)
+	if len(lines) != 4 || lines[1] != strings.Join(HeaderNames(), ",")+",note" {
+		t.Fatalf("file = %q", lines)
+	}
+	for _, line := range lines[2:] {
+		if n := len(strings.Split(line, ",")); n != len(Columns)+1 {
+			t.Errorf("row %q has %d fields", line, n)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "2024-01-01 10:00:00,true,81,") || !strings.HasSuffix(lines[2], ",a") {
+		t.Errorf("row = %q", lines[2])
+	}
+
+	// A current log is left alone
+	if m, err := w.Migrate(); err != nil || m.Changed() {
+		t.Errorf("second Migrate() = %+v, %v", m, err)
+	}
+}
+
+func TestTrimKeepsSchemaMarker(t *testing.T) {
+	w := &Writer{Path: filepath.Join(t.TempDir(), "logs.csv")}
+	for _, ts := range []string{"2024-01-01T10:00:00Z", "2024-01-01T10:01:00Z", "2024-01-01T10:02:00Z"} {
+		if err := w.AppendCSV(record(ts, 80)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.TrimToLast(5); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(t, w); len(lines) != 5 {
+		t.Errorf("trim with fewer rows than the limit changed the file: %q", lines)
+	}
+	if err := w.TrimToLast(1); err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, w)
+	if len(lines) != 3 || lines[0] != "# battery-zen schema 2" || !strings.HasPrefix(lines[2], "2024-01-01T10:02:00Z,") {
+		t.Errorf("file = %q", lines)
+	}
+}
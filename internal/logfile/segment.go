@@ -0,0 +1,605 @@
+package logfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment files start with segmentMagic, then a record holding the column
+// names, then one record per sample. A record is framed as
+//
+//	length  uint32  payload bytes
+//	crc     uint32  CRC-32 (IEEE) of time and payload
+//	time    int64   sample time, Unix nanoseconds
+//	payload         uvarint field count, then uvarint length + bytes per field
+//
+// all little-endian. A record that is short or fails its checksum is
+// skipped: reading resyncs on the next intact record after it. Only damage
+// with no intact record after it, a write torn by a crash, is truncated on
+// the next open.
+const segmentMagic = "BZSEG\x01"
+
+const (
+	recordHeaderSize = 16
+	maxRecordSize    = 1 << 20 // Anything longer is corruption
+)
+
+// SegmentSize is the size at which a new segment is started
+const SegmentSize = 8 << 20
+
+// Every indexEvery-th record of a segment gets a sparse index entry: the
+// record's time and offset, 16 bytes little-endian in the .idx file next
+// to the segment. Range seeks through it instead of scanning from the start.
+const indexEvery = 128
+
+// SegmentDir returns the directory of the segment store for the log at
+// path, e.g. logs.seg next to logs.csv
+func SegmentDir(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".seg"
+}
+
+// SegmentStore keeps every sample in append-only binary segments, named by
+// the Unix time of their first record. It assumes sample times grow, as
+// they do for a logger, and never trims. With Sync set every append is
+// flushed to disk before it returns. Like the CSV log, the store is
+// guarded by an flock on LockPath(Dir).
+type SegmentStore struct {
+	Dir         string
+	Sync        bool
+	LockTimeout time.Duration // Wait for the lock, DefaultLockTimeout if zero
+}
+
+// segment is one file of the store
+type segment struct {
+	path  string
+	first time.Time
+}
+
+func (g segment) indexPath() string {
+	return strings.TrimSuffix(g.path, ".seg") + ".idx"
+}
+
+func (s *SegmentStore) segments() ([]segment, error) {
+	matches, err := filepath.Glob(filepath.Join(s.Dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	var out []segment
+	for _, m := range matches {
+		secs, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(m), ".seg"), 10, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, segment{path: m, first: time.Unix(secs, 0)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].first.Before(out[j].first) })
+	return out, nil
+}
+
+// Append adds one sample
+func (s *SegmentStore) Append(rec Record) error {
+	return s.AppendRows([][]string{rec.Fields()})
+}
+
+// AppendRows adds rows laid out as HeaderNames. Every row needs a timestamp
+// that ParseTimestamp accepts. The last segment is recovered first, and a
+// new one is started when it is full or was written with other columns.
+func (s *SegmentStore) AppendRows(rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	times := make([]time.Time, len(rows))
+	for i, row := range rows {
+		t, err := ParseTimestamp(strings.TrimSpace(row[0]))
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i+1, err)
+		}
+		times[i] = t
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	l, err := lockLog(s.Dir, true, s.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock(l)
+
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	var a *segmentAppender
+	if len(segs) > 0 {
+		if a, err = openAppender(segs[len(segs)-1], s.Sync); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if a != nil {
+			a.close()
+		}
+	}()
+
+	for i, row := range rows {
+		if a == nil || a.size >= SegmentSize || !slices.Equal(a.header, HeaderNames()) {
+			if a != nil {
+				if err := a.close(); err != nil {
+					return err
+				}
+			}
+			if a, err = s.createSegment(times[i]); err != nil {
+				return err
+			}
+		}
+		if err := a.append(times[i], row); err != nil {
+			return err
+		}
+	}
+	err = a.close()
+	a = nil
+	return err
+}
+
+// createSegment starts a segment whose first record is at t. The magic and
+// header are written to a temp file and renamed into place, so a segment
+// never exists without them.
+func (s *SegmentStore) createSegment(t time.Time) (*segmentAppender, error) {
+	// Two segments started within a second: keep names unique and ordered
+	secs := t.Unix()
+	path := filepath.Join(s.Dir, fmt.Sprintf("%012d.seg", secs))
+	for {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		secs++
+		path = filepath.Join(s.Dir, fmt.Sprintf("%012d.seg", secs))
+	}
+
+	a := &segmentAppender{seg: segment{path: path, first: time.Unix(secs, 0)}, header: HeaderNames(), sync: s.Sync}
+	hdr := append([]byte(segmentMagic), encodeRecord(0, a.header)...)
+	if err := writeSynced(path+".tmp", hdr); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	if err := syncDir(s.Dir); err != nil {
+		return nil, err
+	}
+	var err error
+	if a.f, err = os.OpenFile(path, os.O_RDWR, 0o644); err != nil {
+		return nil, err
+	}
+	a.size = int64(len(hdr))
+	if a.idx, err = os.OpenFile(a.seg.indexPath(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644); err != nil {
+		a.f.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// writeSynced writes a new file and flushes it to disk
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// segmentAppender writes records to the end of one segment
+type segmentAppender struct {
+	seg    segment
+	f, idx *os.File
+	header []string
+	size   int64 // Valid bytes, where the next record goes
+	n      int   // Sample records in the segment
+	torn   int64 // Bytes of a torn tail record cut off on open
+	sync   bool  // fsync on close
+}
+
+// openAppender opens the last segment for appending, truncating a torn
+// tail record and any index entries past the valid data
+func openAppender(seg segment, sync bool) (*segmentAppender, error) {
+	f, err := os.OpenFile(seg.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	a := &segmentAppender{seg: seg, f: f, sync: sync}
+	if err := a.recover(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *segmentAppender) recover() error {
+	info, err := a.f.Stat()
+	if err != nil {
+		return err
+	}
+	header, start, err := readSegmentHeader(a.f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(a.seg.path), err)
+	}
+	a.header = header
+
+	entries, err := readIndex(a.seg.indexPath())
+	if err != nil {
+		return err
+	}
+	for len(entries) > 0 && entries[len(entries)-1].offset >= info.Size() {
+		entries = entries[:len(entries)-1]
+	}
+
+	// Scan from the last index entry; fall back to the start if it does
+	// not point at a record
+	from, n := start, 0
+	if k := len(entries); k > 0 {
+		from, n = entries[k-1].offset, (k-1)*indexEvery
+	}
+	end, count, err := scanRecords(a.f, from, info.Size())
+	if err != nil {
+		return err
+	}
+	if count == 0 && from != start {
+		entries = nil
+		from, n = start, 0
+		if end, count, err = scanRecords(a.f, from, info.Size()); err != nil {
+			return err
+		}
+	}
+	a.size, a.n = end, n+count
+
+	if end < info.Size() {
+		if err := a.f.Truncate(end); err != nil {
+			return err
+		}
+		a.torn = info.Size() - end
+	}
+	if a.idx, err = os.OpenFile(a.seg.indexPath(), os.O_CREATE|os.O_WRONLY, 0o644); err != nil {
+		return err
+	}
+	if err := a.idx.Truncate(int64(len(entries)) * 16); err != nil {
+		return err
+	}
+	_, err = a.idx.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (a *segmentAppender) append(t time.Time, fields []string) error {
+	rec := encodeRecord(t.UnixNano(), fields)
+	if _, err := a.f.WriteAt(rec, a.size); err != nil {
+		return err
+	}
+	if a.n%indexEvery == 0 {
+		var e [16]byte
+		binary.LittleEndian.PutUint64(e[0:], uint64(t.UnixNano()))
+		binary.LittleEndian.PutUint64(e[8:], uint64(a.size))
+		if _, err := a.idx.Write(e[:]); err != nil {
+			return err
+		}
+	}
+	a.size += int64(len(rec))
+	a.n++
+	return nil
+}
+
+// close flushes the segment and its index to disk first if the appender
+// syncs. Index entries past the data after a crash are dropped on open.
+func (a *segmentAppender) close() error {
+	var err error
+	if a.sync {
+		err = a.f.Sync()
+		if err == nil && a.idx != nil {
+			err = a.idx.Sync()
+		}
+	}
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	if a.idx != nil {
+		if ierr := a.idx.Close(); err == nil {
+			err = ierr
+		}
+	}
+	return err
+}
+
+func encodeRecord(nanos int64, fields []string) []byte {
+	var payload bytes.Buffer
+	payload.Write(binary.AppendUvarint(nil, uint64(len(fields))))
+	for _, f := range fields {
+		payload.Write(binary.AppendUvarint(nil, uint64(len(f))))
+		payload.WriteString(f)
+	}
+	rec := make([]byte, recordHeaderSize, recordHeaderSize+payload.Len())
+	binary.LittleEndian.PutUint32(rec[0:], uint32(payload.Len()))
+	binary.LittleEndian.PutUint64(rec[8:], uint64(nanos))
+	rec = append(rec, payload.Bytes()...)
+	binary.LittleEndian.PutUint32(rec[4:], crc32.ChecksumIEEE(rec[8:]))
+	return rec
+}
+
+var errTorn = errors.New("torn record")
+
+// readRecord reads the record at the reader's position. A short or
+// corrupt record yields errTorn; a clean end of segment io.EOF.
+func readRecord(r io.Reader) (int64, []string, int, error) {
+	var hdr [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, 0, io.EOF
+		}
+		return 0, nil, 0, errTorn
+	}
+	size := binary.LittleEndian.Uint32(hdr[0:])
+	if size > maxRecordSize {
+		return 0, nil, 0, errTorn
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, 0, errTorn
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[8:])
+	crc.Write(payload)
+	if crc.Sum32() != binary.LittleEndian.Uint32(hdr[4:]) {
+		return 0, nil, 0, errTorn
+	}
+	fields, ok := decodeFields(payload)
+	if !ok {
+		return 0, nil, 0, errTorn
+	}
+	return int64(binary.LittleEndian.Uint64(hdr[8:])), fields, recordHeaderSize + int(size), nil
+}
+
+func decodeFields(b []byte) ([]string, bool) {
+	n, k := binary.Uvarint(b)
+	if k <= 0 || n > uint64(len(b)) {
+		return nil, false
+	}
+	b = b[k:]
+	fields := make([]string, n)
+	for i := range fields {
+		l, k := binary.Uvarint(b)
+		if k <= 0 || l > uint64(len(b)-k) {
+			return nil, false
+		}
+		fields[i] = string(b[k : k+int(l)])
+		b = b[k+int(l):]
+	}
+	return fields, len(b) == 0
+}
+
+// readSegmentHeader checks the magic and returns the column names and the
+// offset of the first sample record
+func readSegmentHeader(f *os.File) ([]string, int64, error) {
+	r := bufio.NewReader(io.NewSectionReader(f, 0, 1<<62))
+	magic := make([]byte, len(segmentMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != segmentMagic {
+		return nil, 0, errors.New("not a battery-zen segment")
+	}
+	_, header, n, err := readRecord(r)
+	if err != nil {
+		return nil, 0, errors.New("segment header damaged")
+	}
+	return header, int64(len(segmentMagic) + n), nil
+}
+
+// scanRecords validates the records from offset from and returns the end
+// of the last intact one and how many there were
+func scanRecords(f *os.File, from, size int64) (int64, int, error) {
+	b, err := readFrom(f, from, size)
+	if err != nil {
+		return from, 0, err
+	}
+	end, count := walkRecords(b, from, nil)
+	return end, count, nil
+}
+
+// readFrom reads f from offset from up to size
+func readFrom(f *os.File, from, size int64) ([]byte, error) {
+	if size <= from {
+		return nil, nil
+	}
+	b := make([]byte, size-from)
+	n, err := f.ReadAt(b, from)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return b[:n], nil
+}
+
+// walkRecords calls emit with the time and fields of every intact record in
+// b, which starts at file offset base, until emit returns false. A damaged
+// record is skipped by resyncing on the next offset where an intact record
+// starts that is no older than the last good one, so one bad record never
+// hides the ones after it. It returns the offset just past the last intact
+// record and how many intact records there were.
+func walkRecords(b []byte, base int64, emit func(nanos int64, fields []string) bool) (int64, int) {
+	end, count := base, 0
+	last := int64(math.MinInt64)
+	resyncing := false
+	for off := 0; off+recordHeaderSize <= len(b); {
+		floor := int64(math.MinInt64)
+		if resyncing {
+			floor = last
+		}
+		nanos, fields, n, ok := parseRecord(b[off:], floor)
+		if !ok {
+			resyncing = true
+			off++
+			continue
+		}
+		resyncing = false
+		last = nanos
+		off += n
+		end = base + int64(off)
+		count++
+		if emit != nil && !emit(nanos, fields) {
+			break
+		}
+	}
+	return end, count
+}
+
+// parseRecord decodes the record at the start of b. It reports false unless
+// b starts with a whole record that passes its checksum, dated no earlier
+// than minNanos; the date is checked first to make resyncing cheap.
+func parseRecord(b []byte, minNanos int64) (int64, []string, int, bool) {
+	if len(b) < recordHeaderSize {
+		return 0, nil, 0, false
+	}
+	size := binary.LittleEndian.Uint32(b[0:])
+	if size > maxRecordSize || int(size) > len(b)-recordHeaderSize {
+		return 0, nil, 0, false
+	}
+	nanos := int64(binary.LittleEndian.Uint64(b[8:]))
+	if nanos < minNanos {
+		return 0, nil, 0, false
+	}
+	n := recordHeaderSize + int(size)
+	if crc32.ChecksumIEEE(b[8:n]) != binary.LittleEndian.Uint32(b[4:]) {
+		return 0, nil, 0, false
+	}
+	fields, ok := decodeFields(b[recordHeaderSize:n])
+	if !ok {
+		return 0, nil, 0, false
+	}
+	return nanos, fields, n, true
+}
+
+type indexEntry struct {
+	t      int64
+	offset int64
+}
+
+// readIndex loads a segment's sparse index, ignoring a torn last entry. A
+// missing index is empty.
+func readIndex(path string) ([]indexEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entries := make([]indexEntry, len(b)/16)
+	for i := range entries {
+		entries[i] = indexEntry{
+			t:      int64(binary.LittleEndian.Uint64(b[i*16:])),
+			offset: int64(binary.LittleEndian.Uint64(b[i*16+8:])),
+		}
+	}
+	return entries, nil
+}
+
+// Range returns the samples logged from from up to (not including) to,
+// reading only the segments that can hold them and seeking through the
+// sparse index. Damaged records are skipped quietly.
+func (s *SegmentStore) Range(from, to time.Time) ([][]string, error) {
+	l, err := lockLog(s.Dir, false, s.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock(l)
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		if _, err := os.Stat(s.Dir); err != nil {
+			return nil, err
+		}
+	}
+	out := [][]string{HeaderNames()}
+	for i, seg := range segs {
+		if !to.IsZero() && !seg.first.Before(to) {
+			break
+		}
+		if !from.IsZero() && i+1 < len(segs) && !segs[i+1].first.After(from) {
+			continue
+		}
+		if err := seg.read(from, to, func(row []string) { out = append(out, row) }); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (g segment) read(from, to time.Time, emit func([]string)) error {
+	f, err := os.Open(g.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header, start, err := readSegmentHeader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(g.path), err)
+	}
+	src := make([]int, len(Columns))
+	for j, c := range Columns {
+		src[j] = c.Index(header)
+	}
+
+	if !from.IsZero() {
+		entries, err := readIndex(g.indexPath())
+		if err != nil {
+			return err
+		}
+		// Last entry before from; records before it are all earlier
+		k := sort.Search(len(entries), func(i int) bool { return entries[i].t >= from.UnixNano() })
+		if k > 0 {
+			start = entries[k-1].offset
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	b, err := readFrom(f, start, info.Size())
+	if err != nil {
+		return err
+	}
+	walkRecords(b, start, func(nanos int64, fields []string) bool {
+		if !from.IsZero() && nanos < from.UnixNano() {
+			return true
+		}
+		if !to.IsZero() && nanos >= to.UnixNano() {
+			return false
+		}
+		row := make([]string, len(Columns))
+		for j, i := range src {
+			if i >= 0 && i < len(fields) {
+				row[j] = fields[i]
+			}
+		}
+		emit(row)
+		return true
+	})
+	return nil
+}
@@ -0,0 +1,160 @@
+package logfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var segmentT0 = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+func minuteRows(start, n int) [][]string {
+	rows := make([][]string, n)
+	for i := range rows {
+		rec := record(segmentT0.Add(time.Duration(start+i)*time.Minute).Format(time.RFC3339), 100-(start+i)%100)
+		rows[i] = rec.Fields()
+	}
+	return rows
+}
+
+func TestSegmentStoreRange(t *testing.T) {
+	s := &SegmentStore{Dir: filepath.Join(t.TempDir(), "logs.seg")}
+	// Two appends so the second one recovers the segment and its index
+	if err := s.AppendRows(minuteRows(0, 300)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendRows(minuteRows(300, 300)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readIndex(filepath.Join(s.Dir, "001788220800.idx"))
+	if err != nil || len(entries) != 5 {
+		t.Fatalf("index = %d entries, %v", len(entries), err)
+	}
+
+	all, err := s.Range(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 601 || all[1][0] != "2026-09-01T00:00:00Z" || all[600][0] != "2026-09-01T09:59:00Z" {
+		t.Fatalf("Range() = %d records", len(all))
+	}
+
+	got, err := s.Range(segmentT0.Add(200*time.Minute), segmentT0.Add(210*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 11 || got[1][0] != "2026-09-01T03:20:00Z" || got[10][0] != "2026-09-01T03:29:00Z" {
+		t.Errorf("bounded Range() = %q", got[1:])
+	}
+	if got[1][2] != "100" || got[1][7] != "7.500" {
+		t.Errorf("fields = %q", got[1])
+	}
+}
+
+func TestSegmentStoreTornTail(t *testing.T) {
+	s := &SegmentStore{Dir: filepath.Join(t.TempDir(), "logs.seg")}
+	if err := s.AppendRows(minuteRows(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	segs, err := s.segments()
+	if err != nil || len(segs) != 1 {
+		t.Fatalf("segments() = %v, %v", segs, err)
+	}
+	path := segs[0].path
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lose the end of the last record, as a crash mid-write would
+	if err := os.Truncate(path, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := s.Range(time.Time{}, time.Time{})
+	if err != nil || len(rows) != 10 {
+		t.Fatalf("Range() over a torn tail = %d records, %v", len(rows), err)
+	}
+	if err := s.AppendRows(minuteRows(10, 1)); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = s.Range(time.Time{}, time.Time{})
+	if err != nil || len(rows) != 11 || rows[10][0] != "2026-09-01T00:10:00Z" {
+		t.Fatalf("Range() after recovery = %d records, %v", len(rows), err)
+	}
+	if rows[9][0] != "2026-09-01T00:08:00Z" {
+		t.Errorf("torn record not dropped: %q", rows[9][0])
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	src := &CSVStore{Path: filepath.Join(dir, "logs.csv")}
+	if err := src.AppendRows(minuteRows(0, 20)); err != nil {
+		t.Fatal(err)
+	}
+	seg := &SegmentStore{Dir: SegmentDir(src.Path)}
+	n, skipped, err := Import(src.Path, seg)
+	if err != nil || n != 20 || skipped != 0 {
+		t.Fatalf("Import() = %d, %d, %v", n, skipped, err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := Export(&buf, seg, segmentT0.Add(15*time.Minute), time.Time{}); err != nil || n != 5 {
+		t.Fatalf("Export() = %d, %v", n, err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 7 || lines[0] != "# battery-zen schema 2" || !strings.HasPrefix(lines[2], "2026-09-01T00:15:00Z,") {
+		t.Errorf("export = %q", lines)
+	}
+}
+
+func TestSegmentStoreCorruptRecord(t *testing.T) {
+	s := &SegmentStore{Dir: filepath.Join(t.TempDir(), "logs.seg")}
+	if err := s.AppendRows(minuteRows(0, 300)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(s.Dir, "001788220800.seg")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte in minute 100, inside the index, and in minute 280,
+	// after the last index entry
+	for _, ts := range []string{"2026-09-01T01:40:00Z", "2026-09-01T04:40:00Z"} {
+		i := bytes.Index(b, []byte(ts))
+		if i < 0 {
+			t.Fatalf("%s not found", ts)
+		}
+		b[i] ^= 0xff
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(what string, want int) {
+		t.Helper()
+		rows, err := s.Range(time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows)-1 != want || rows[101][0] != "2026-09-01T01:41:00Z" || rows[280][0] != "2026-09-01T04:41:00Z" {
+			t.Errorf("%s: Range() = %d rows", what, len(rows)-1)
+		}
+	}
+	check("after corruption", 298)
+	if got, err := s.Range(segmentT0.Add(290*time.Minute), time.Time{}); err != nil || len(got) != 11 {
+		t.Errorf("Range() after the damage = %d records, %v", len(got), err)
+	}
+
+	// Opening for append must not cut the good records after the damage
+	if rec, err := s.Recover(); err != nil || rec.Torn != 0 {
+		t.Fatalf("Recover() = %+v, %v", rec, err)
+	}
+	if err := s.AppendRows(minuteRows(300, 1)); err != nil {
+		t.Fatal(err)
+	}
+	check("after append", 299)
+}
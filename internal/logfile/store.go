@@ -0,0 +1,98 @@
+package logfile
+
+import (
+	"fmt"
+	"time"
+)
+
+// Store is a backend for the sample log. Rows go in and come out laid out
+// as HeaderNames, so any backend can be exported to another.
+type Store interface {
+	// Append adds one sample
+	Append(rec Record) error
+	// AppendRows adds rows laid out as HeaderNames, e.g. from another store
+	AppendRows(rows [][]string) error
+	// Range returns the rows logged from from up to (not including) to,
+	// oldest first, with HeaderNames as the first record. A zero from or
+	// to leaves that end open.
+	Range(from, to time.Time) ([][]string, error)
+	// Recover repairs what a crash during a write left behind
+	Recover() (Recovery, error)
+}
+
+// Storage backends selectable in the config
+const (
+	StorageCSV     = "csv"
+	StorageSegment = "segment"
+)
+
+// CSVStore keeps samples in the CSV log, trimmed to MaxLines rows once it
+// grows TrimBuffer past that. With Archive set the trimmed rows move into
+// monthly archives, which Range reads too; otherwise they are dropped.
+// MaxLines of 0 disables trimming. Sync flushes every append to disk, and
+// LockTimeout bounds the wait for another process using the log.
+type CSVStore struct {
+	Path        string
+	MaxLines    int
+	TrimBuffer  int
+	Archive     bool
+	Sync        bool
+	LockTimeout time.Duration
+}
+
+func (s *CSVStore) writer() *Writer {
+	return &Writer{Path: s.Path, Sync: s.Sync, LockTimeout: s.LockTimeout}
+}
+
+// Append writes the sample and trims the log when it outgrew the limit
+func (s *CSVStore) Append(rec Record) error {
+	return s.AppendRows([][]string{rec.Fields()})
+}
+
+// AppendRows writes the rows and trims the log when it outgrew the limit
+func (s *CSVStore) AppendRows(rows [][]string) error {
+	w := s.writer()
+	if err := w.AppendRows(rows); err != nil {
+		return err
+	}
+	if s.MaxLines <= 0 {
+		return nil
+	}
+	lines, err := w.LineCount()
+	if err == nil && lines > s.MaxLines+s.TrimBuffer+2 { // +2 schema marker and header
+		return s.Trim()
+	}
+	return nil
+}
+
+// Trim cuts the log to MaxLines rows now
+func (s *CSVStore) Trim() error {
+	if s.Archive {
+		return s.writer().Rotate(s.MaxLines)
+	}
+	return s.writer().TrimToLast(s.MaxLines)
+}
+
+// Range reads the archives and the live log
+func (s *CSVStore) Range(from, to time.Time) ([][]string, error) {
+	return (&Reader{Path: s.Path, LockTimeout: s.LockTimeout}).ReadRange(from, to)
+}
+
+// Recover cuts a torn final row and removes a stale temp file
+func (s *CSVStore) Recover() (Recovery, error) {
+	return s.writer().Recover()
+}
+
+// Open returns the store for the named backend. csv is the log file at
+// path, trimmed per the CSVStore fields; segment keeps every sample in
+// binary segments in the directory SegmentDir(path). Both sync and wait
+// for locks per csv.
+func Open(storage string, csv CSVStore) (Store, error) {
+	switch storage {
+	case StorageCSV, "":
+		return &csv, nil
+	case StorageSegment:
+		return &SegmentStore{Dir: SegmentDir(csv.Path), Sync: csv.Sync, LockTimeout: csv.LockTimeout}, nil
+	}
+	return nil, fmt.Errorf("unknown storage %q (want %s or %s)", storage, StorageCSV, StorageSegment)
+}
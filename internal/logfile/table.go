@@ -0,0 +1,104 @@
+package logfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// Table is a side log with a fixed header and no schema marker, such as
+// the health, peripheral, process, suspend or version history. Appends and
+// trims take the file's lock and cut a torn last line like the sample log.
+type Table struct {
+	Path        string
+	Header      []string
+	LockTimeout time.Duration // Wait for the file's lock, DefaultLockTimeout if zero
+}
+
+// Load returns the data rows in file order, skipping the header and rows
+// with fewer fields than it. A missing file yields none.
+func (t *Table) Load() ([][]string, error) {
+	l, err := lockLog(t.Path, false, t.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock(l)
+
+	f, err := os.Open(t.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	recs, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var out [][]string
+	for i, rec := range recs {
+		if i == 0 || len(rec) < len(t.Header) {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// Append writes rows, starting the file with the header if it is new
+func (t *Table) Append(rows ...[]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	l, err := lockLog(t.Path, true, t.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock(l)
+
+	w := &Writer{Path: t.Path}
+	if _, err := w.truncateTorn(); err != nil {
+		return err
+	}
+	info, err := os.Stat(t.Path)
+	newFile := errors.Is(err, os.ErrNotExist) || err == nil && info.Size() == 0
+
+	f, err := os.OpenFile(t.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	if newFile {
+		if _, err := bw.WriteString(strings.Join(t.Header, ",") + "\n"); err != nil {
+			return err
+		}
+	}
+	cw := csv.NewWriter(bw)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Trim keeps the header and the last maxRows rows once the file has grown
+// buffer rows past that. maxRows of 0 disables trimming.
+func (t *Table) Trim(maxRows, buffer int) error {
+	if maxRows <= 0 {
+		return nil
+	}
+	w := &Writer{Path: t.Path, LockTimeout: t.LockTimeout}
+	lines, err := w.LineCount()
+	if err != nil || lines <= maxRows+buffer+1 { // +1 header
+		return err
+	}
+	return w.TrimToLast(maxRows)
+}
@@ -0,0 +1,38 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	tb := &Table{Path: filepath.Join(t.TempDir(), "peripherals.csv"), Header: []string{"timestamp", "device", "percent"}}
+	if rows, err := tb.Load(); err != nil || rows != nil {
+		t.Fatalf("Load() of a missing file = %q, %v", rows, err)
+	}
+	if err := tb.Append([]string{"2026-09-01T00:00:00Z", "hidpp", "50"}, []string{"2026-09-01T00:01:00Z", "a,b", "49"}); err != nil {
+		t.Fatal(err)
+	}
+	// A short row and a torn one from a crash
+	f, err := os.OpenFile(tb.Path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("2026-09-01T00:02:00Z,short\n2026-09-01T00:0")
+	f.Close()
+	if err := tb.Append([]string{"2026-09-01T00:03:00Z", "hidpp", "48"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := tb.Load()
+	if err != nil || len(rows) != 3 || rows[1][1] != "a,b" || rows[2][0] != "2026-09-01T00:03:00Z" {
+		t.Fatalf("Load() = %q, %v", rows, err)
+	}
+	if err := tb.Trim(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := tb.Load(); err != nil || len(rows) != 1 || rows[0][2] != "48" {
+		t.Errorf("Load() after Trim() = %q, %v", rows, err)
+	}
+}
@@ -2,22 +2,137 @@ package logfile
 
 import (
 	"bufio"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Writer struct {
-	Path string
+	Path        string
+	Sync        bool          // fsync after every append, not just before a replace
+	LockTimeout time.Duration // Wait for the log's lock, DefaultLockTimeout if zero
 }
 
-// Append a CSV row (write header if file didn't exist)
-func (w *Writer) AppendCSV(timestamp string, ac bool, pct int) error {
-	_, err := os.Stat(w.Path)
-	newFile := errors.Is(err, os.ErrNotExist)
+// PackLevel is the charge of one battery pack within a Record.
+type PackLevel struct {
+	Name    string
+	Percent int
+}
+
+// Record is a single logged sample. Percent is the combined level of all
+// packs; Packs holds the per-battery breakdown. Energy and power values
+// are NaN when the battery does not expose them and are written as empty
+// fields.
+type Record struct {
+	Timestamp        string
+	AC               bool
+	Percent          int
+	Packs            []PackLevel
+	EnergyNow        float64 // Wh
+	EnergyFull       float64 // Wh
+	EnergyFullDesign float64 // Wh
+	Power            float64 // W
+	Status           string
+	CapacityLevel    string
+	Event            string // Set on rows recording a change made by battery-zen
+	ChargeLimit      int    // Firmware charge end threshold, -1 if none
+	Charger          string // Name of the active external supply
+	ChargerType      string // e.g. Mains, USB/PD
+	ChargerMaxW      float64
+	ScreenOn         int     // 1 on, 0 off, -1 unknown
+	IdleSecs         int     // Seconds since the last keyboard/touchpad/mouse input, -1 unknown
+	CPUUtil          float64 // % busy since the previous sample
+	LoadAvg          float64 // 1-minute load average
+	CPUFreqMHz       float64 // Mean scaling_cur_freq
+	PlatformProfile  string  // e.g. low-power, balanced, performance
+	SuspendedSecs    float64 // Time suspended since boot (CLOCK_BOOTTIME - CLOCK_MONOTONIC)
+	BootID           string  // /proc/sys/kernel/random/boot_id
+	UptimeSecs       float64
+	LidOpen          int // 1 open, 0 closed, -1 no lid switch
+	ExternalDisplays int // Connected DRM outputs besides the built-in panel, -1 unknown
+}
+
+func formatFloat(v float64, prec int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+func formatOptionalInt(v int) string {
+	if v < 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// Fields formats the record in the order of HeaderNames
+func (r Record) Fields() []string {
+	fields := make([]string, len(Columns))
+	for i, c := range Columns {
+		fields[i] = c.format(r)
+	}
+	return fields
+}
+
+// FormatPacks encodes the per-battery breakdown as "BAT0=85;BAT1=60".
+func FormatPacks(packs []PackLevel) string {
+	parts := make([]string, len(packs))
+	for i, p := range packs {
+		parts[i] = fmt.Sprintf("%s=%d", p.Name, p.Percent)
+	}
+	return strings.Join(parts, ";")
+}
+
+// AppendCSV appends a row, creating the file with a schema marker and the
+// current header if needed. An existing log that lacks registered columns
+// gets them added to its header first, so rows always line up with it.
+func (w *Writer) AppendCSV(rec Record) error {
+	return w.AppendRows([][]string{rec.Fields()})
+}
+
+// AppendRows appends rows laid out as HeaderNames, reordered to the file's
+// columns, like AppendCSV. A torn final line is cut first so the new rows
+// do not run on from it.
+func (w *Writer) AppendRows(rows [][]string) error {
+	l, err := lockLog(w.Path, true, w.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock(l)
+	if _, err := w.truncateTorn(); err != nil {
+		return err
+	}
+	s, err := ReadSchema(w.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	newFile := len(s.Header) == 0
+	if newFile {
+		s.Header = HeaderNames()
+	} else if !s.Current() {
+		if s, err = w.upgradeHeader(s); err != nil {
+			return err
+		}
+	}
+	// src[i] is the registry index of file column i, -1 if unregistered
+	src := make([]int, len(s.Header))
+	for i, h := range s.Header {
+		src[i] = -1
+		for j, c := range Columns {
+			if c.matches(h) {
+				src[i] = j
+				break
+			}
+		}
+	}
 
 	f, err := os.OpenFile(w.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 	if err != nil {
@@ -27,22 +142,120 @@ func (w *Writer) AppendCSV(timestamp string, ac bool, pct int) error {
 
 	bw := bufio.NewWriter(f)
 	if newFile {
-		if _, err := bw.WriteString("timestamp,ac_connected,battery_life\n"); err != nil {
+		if _, err := bw.WriteString(headerBlock(s.Header)); err != nil {
+			return err
+		}
+	}
+	cw := csv.NewWriter(bw)
+	out := make([]string, len(s.Header))
+	for _, row := range rows {
+		for i, j := range src {
+			out[i] = ""
+			if j >= 0 && j < len(row) {
+				out[i] = row[j]
+			}
+		}
+		if err := cw.Write(out); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if !w.Sync {
+		return nil
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if newFile {
+		return syncDir(filepath.Dir(w.Path))
+	}
+	return nil
+}
+
+// upgradeHeader appends the missing registered columns to the header and
+// stamps the current schema version (atomic replace). Data rows are copied
+// as they are: readers treat the fields they lack as empty.
+func (w *Writer) upgradeHeader(s Schema) (Schema, error) {
+	header := append([]string(nil), s.Header...)
+	for _, c := range s.Missing() {
+		header = append(header, c.Name)
+	}
+
+	src, err := os.Open(w.Path)
+	if err != nil {
+		return s, err
+	}
+	defer src.Close()
+	br := bufio.NewReader(src)
+	if _, _, err := readSchema(br); err != nil {
+		return s, err
+	}
+
+	err = w.replace(func(bw *bufio.Writer) error {
+		if _, err := bw.WriteString(headerBlock(header)); err != nil {
 			return err
 		}
+		_, err := io.Copy(bw, br)
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+	return Schema{Version: SchemaVersion, Header: header}, nil
+}
+
+// replace writes a new version of the file through fill and renames it over
+// the old one. The new file is on disk before the rename, so a crash leaves
+// either version whole; a failed replace removes its temp file.
+func (w *Writer) replace(fill func(*bufio.Writer) error) (err error) {
+	tmp := w.Path + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	bw := bufio.NewWriter(dst)
+	if err := fill(bw); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		return err
 	}
-	acInt := 0
-	if ac {
-		acInt = 1
+	if err := dst.Close(); err != nil {
+		return err
 	}
-	if _, err := bw.WriteString(fmt.Sprintf("%s,%d,%d\n", timestamp, acInt, pct)); err != nil {
+	if err := os.Rename(tmp, w.Path); err != nil { // atomic within same dir
 		return err
 	}
-	return bw.Flush()
+	return syncDir(filepath.Dir(w.Path))
 }
 
 // Count lines quickly enough for ~1k lines
 func (w *Writer) LineCount() (int, error) {
+	l, err := lockLog(w.Path, false, w.LockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock(l)
+	return w.lineCount()
+}
+
+func (w *Writer) lineCount() (int, error) {
 	f, err := os.Open(w.Path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -67,8 +280,17 @@ func (w *Writer) LineCount() (int, error) {
 	return count, nil
 }
 
-// Keep header + last N data lines (atomic replace)
+// Keep header + last N data lines (atomic replace). The schema marker, if
+// any, is kept with the header; a torn final line is dropped.
 func (w *Writer) TrimToLast(maxDataLines int) error {
+	l, err := lockLog(w.Path, true, w.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock(l)
+	if _, err := w.truncateTorn(); err != nil {
+		return err
+	}
 	// Read existing file; if not found, nothing to do
 	src, err := os.Open(w.Path)
 	if err != nil {
@@ -79,54 +301,47 @@ func (w *Writer) TrimToLast(maxDataLines int) error {
 	}
 	defer src.Close()
 
-	// Read header
-	br := bufio.NewReader(src)
-	header, err := br.ReadString('\n')
-	if err != nil && !errors.Is(err, io.EOF) {
+	// Read header block (marker and header lines)
+	_, headerLen, err := readSchema(bufio.NewReader(src))
+	if err != nil {
 		return err
 	}
-	// Tail last N data lines by reading file backwards
-	dataLines, err := tailLastLines(src, maxDataLines)
-	if err != nil {
+	header := make([]byte, headerLen)
+	if _, err := src.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
 		return err
 	}
-
-	tmp := w.Path + ".tmp"
-	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	// Tail last N data lines by reading file backwards
+	dataLines, err := tailLastLines(src, headerLen, maxDataLines)
 	if err != nil {
 		return err
 	}
-	defer dst.Close()
 
-	bw := bufio.NewWriter(dst)
-	if header != "" {
-		if _, err := bw.WriteString(header); err != nil {
+	return w.replace(func(bw *bufio.Writer) error {
+		if _, err := bw.Write(header); err != nil {
 			return err
 		}
-	}
-	for i := range dataLines {
-		if _, err := bw.WriteString(dataLines[i]); err != nil {
-			return err
+		for i := range dataLines {
+			if _, err := bw.WriteString(dataLines[i]); err != nil {
+				return err
+			}
 		}
-	}
-	if err := bw.Flush(); err != nil {
-		return err
-	}
-	return os.Rename(tmp, w.Path) // atomic within same dir
+		return nil
+	})
 }
 
-// tailLastLines reads the last N lines (excluding header) efficiently.
-func tailLastLines(f *os.File, n int) ([]string, error) {
+// tailLastLines reads the last N lines after offset from (the header block)
+// efficiently.
+func tailLastLines(f *os.File, from int64, n int) ([]string, error) {
 	info, err := f.Stat()
 	if err != nil {
 		return nil, err
 	}
 	size := info.Size()
-	if size == 0 {
+	if size <= from {
 		return nil, nil
 	}
 
-	lines, err := readLinesBackward(f, size, n)
+	lines, err := readLinesBackward(f, from, size, n)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +359,7 @@ func tailLastLines(f *os.File, n int) ([]string, error) {
 	return lines, nil
 }
 
-func readLinesBackward(f *os.File, size int64, n int) ([]string, error) {
+func readLinesBackward(f *os.File, from, size int64, n int) ([]string, error) {
 	const chunk = 8192
 	var (
 		buf   []byte
@@ -153,8 +368,8 @@ func readLinesBackward(f *os.File, size int64, n int) ([]string, error) {
 	)
 
 	partial := []byte{}
-	for pos > 0 && len(lines) <= n {
-		readSize := pos
+	for pos > from && len(lines) <= n {
+		readSize := pos - from
 		if readSize > chunk {
 			readSize = chunk
 		}
@@ -0,0 +1,112 @@
+// Package logind follows systemd-logind's suspend announcements on the
+// system bus, holding a delay lock so work can be done before the system
+// sleeps.
+package logind
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	service = "org.freedesktop.login1"
+	path    = dbus.ObjectPath("/org/freedesktop/login1")
+	manager = "org.freedesktop.login1.Manager"
+)
+
+// SleepWatcher delivers PrepareForSleep on C: true as the system is about
+// to suspend or hibernate, false once it has resumed. While a delay lock is
+// held logind waits for Release, up to its InhibitDelayMaxSec, before
+// sleeping.
+type SleepWatcher struct {
+	C <-chan bool
+
+	conn    *dbus.Conn
+	why     string
+	signals chan *dbus.Signal
+	lock    int // Inhibitor fd, -1 if none is held
+}
+
+// WatchSleep subscribes to PrepareForSleep on conn and takes a delay lock,
+// with why as the reason systemd-inhibit --list shows
+func WatchSleep(conn *dbus.Conn, why string) (*SleepWatcher, error) {
+	w := &SleepWatcher{conn: conn, why: why, lock: -1}
+	if err := conn.AddMatchSignal(w.match()...); err != nil {
+		return nil, fmt.Errorf("logind: %w", err)
+	}
+	if err := w.Inhibit(); err != nil {
+		conn.RemoveMatchSignal(w.match()...)
+		return nil, err
+	}
+	w.signals = make(chan *dbus.Signal, 4)
+	conn.Signal(w.signals)
+
+	c := make(chan bool, 4)
+	w.C = c
+	go func() {
+		defer close(c)
+		for s := range w.signals {
+			if sleeping, ok := prepareForSleep(s); ok {
+				c <- sleeping
+			}
+		}
+	}()
+	return w, nil
+}
+
+func (w *SleepWatcher) match() []dbus.MatchOption {
+	return []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(manager),
+		dbus.WithMatchMember("PrepareForSleep"),
+	}
+}
+
+// prepareForSleep reads a PrepareForSleep signal, ignoring any other
+// signal delivered on the shared connection
+func prepareForSleep(s *dbus.Signal) (sleeping, ok bool) {
+	if s == nil || s.Path != path || s.Name != manager+".PrepareForSleep" || len(s.Body) != 1 {
+		return false, false
+	}
+	sleeping, ok = s.Body[0].(bool)
+	return sleeping, ok
+}
+
+// Inhibit takes the delay lock, if not already held. Call it again after
+// each resume: logind does not restore a lock that was released.
+func (w *SleepWatcher) Inhibit() error {
+	if w.lock >= 0 {
+		return nil
+	}
+	var fd dbus.UnixFD
+	err := w.conn.Object(service, path).Call(manager+".Inhibit", 0,
+		"sleep", "battery-zen", w.why, "delay").Store(&fd)
+	if err != nil {
+		var dbusErr dbus.Error
+		if errors.As(err, &dbusErr) && dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" {
+			return errors.New("logind: not running")
+		}
+		return fmt.Errorf("logind: %w", err)
+	}
+	w.lock = int(fd)
+	return nil
+}
+
+// Release lets the pending suspend go ahead
+func (w *SleepWatcher) Release() {
+	if w.lock >= 0 {
+		unix.Close(w.lock)
+		w.lock = -1
+	}
+}
+
+// Close releases the lock and stops the watch. C is closed.
+func (w *SleepWatcher) Close() {
+	w.Release()
+	w.conn.RemoveSignal(w.signals)
+	w.conn.RemoveMatchSignal(w.match()...)
+	close(w.signals)
+}
@@ -0,0 +1,28 @@
+package logind
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestPrepareForSleep(t *testing.T) {
+	cases := []struct {
+		name         string
+		sig          *dbus.Signal
+		sleeping, ok bool
+	}{
+		{"suspend", &dbus.Signal{Path: path, Name: manager + ".PrepareForSleep", Body: []any{true}}, true, true},
+		{"resume", &dbus.Signal{Path: path, Name: manager + ".PrepareForSleep", Body: []any{false}}, false, true},
+		{"shutdown", &dbus.Signal{Path: path, Name: manager + ".PrepareForShutdown", Body: []any{true}}, false, false},
+		{"other object", &dbus.Signal{Path: "/org/bluez/hci0", Name: manager + ".PrepareForSleep", Body: []any{true}}, false, false},
+		{"bad body", &dbus.Signal{Path: path, Name: manager + ".PrepareForSleep", Body: []any{"yes"}}, false, false},
+		{"closed", nil, false, false},
+	}
+	for _, c := range cases {
+		sleeping, ok := prepareForSleep(c.sig)
+		if sleeping != c.sleeping || ok != c.ok {
+			t.Errorf("%s: prepareForSleep() = %v, %v", c.name, sleeping, ok)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package peripheral logs the battery level of mice, keyboards and headsets
+// found in sysfs (scope=Device) or through BlueZ, and flags low devices.
+package peripheral
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/bluez"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+)
+
+var header = []string{"timestamp", "device", "name", "source", "percent"}
+
+// Sources a reading can come from
+const (
+	SourceSysfs = "sysfs"
+	SourceBlueZ = "bluez"
+)
+
+// Reading is the level of one peripheral at a point in time
+type Reading struct {
+	T       time.Time
+	Device  string // Stable key: sysfs entry name or Bluetooth address
+	Name    string // Human-readable name, may be empty
+	Source  string
+	Percent float64
+}
+
+// Label returns the name if known, otherwise the device key
+func (r Reading) Label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Device
+}
+
+// Collect merges sysfs and BlueZ peripherals into readings. A Bluetooth HID
+// device shows up in both (as hid-<mac>-battery in sysfs); the sysfs entry
+// wins since it is what the kernel driver reports directly.
+func Collect(sys []sysfs.Peripheral, bt []bluez.Battery, t time.Time) []Reading {
+	var out []Reading
+	seen := make(map[string]bool)
+	for _, p := range sys {
+		out = append(out, Reading{T: t, Device: p.Name, Name: p.Model, Source: SourceSysfs, Percent: p.Percent})
+		seen[strings.ToLower(p.Name)] = true
+	}
+	for _, b := range bt {
+		if b.Address == "" || coveredBySysfs(seen, b.Address) {
+			continue
+		}
+		out = append(out, Reading{T: t, Device: b.Address, Name: b.Name, Source: SourceBlueZ, Percent: float64(b.Percent)})
+	}
+	return out
+}
+
+func coveredBySysfs(sysNames map[string]bool, address string) bool {
+	mac := strings.ToLower(address)
+	for name := range sysNames {
+		if strings.Contains(name, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is the append-only per-device log, kept apart from the main sample
+// log because the set of devices changes as they connect and disconnect.
+type Store struct {
+	Path string
+}
+
+func (s *Store) table() *logfile.Table {
+	return &logfile.Table{Path: s.Path, Header: header}
+}
+
+// Load reads all readings in file order. A missing file yields no readings.
+func (s *Store) Load() ([]Reading, error) {
+	recs, err := s.table().Load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Reading
+	for _, rec := range recs {
+		t, err := time.Parse(time.RFC3339, rec[0])
+		if err != nil {
+			continue
+		}
+		pct, err := strconv.ParseFloat(rec[4], 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, Reading{T: t, Device: rec[1], Name: rec[2], Source: rec[3], Percent: pct})
+	}
+	return out, nil
+}
+
+// Append writes readings, creating the file with a header if needed
+func (s *Store) Append(readings []Reading) error {
+	rows := make([][]string, len(readings))
+	for i, r := range readings {
+		rows[i] = []string{
+			r.T.Format(time.RFC3339), r.Device, r.Name, r.Source,
+			strconv.FormatFloat(r.Percent, 'f', 0, 64),
+		}
+	}
+	return s.table().Append(rows...)
+}
+
+// Trim keeps the last maxRows readings once the file is buffer rows past that
+func (s *Store) Trim(maxRows, buffer int) error {
+	return s.table().Trim(maxRows, buffer)
+}
+
+// Series groups readings by device, ordered by label
+func Series(readings []Reading) [][]Reading {
+	byDevice := make(map[string][]Reading)
+	for _, r := range readings {
+		byDevice[r.Device] = append(byDevice[r.Device], r)
+	}
+	out := make([][]Reading, 0, len(byDevice))
+	for _, rs := range byDevice {
+		out = append(out, rs)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i][len(out[i])-1].Label() < out[j][len(out[j])-1].Label()
+	})
+	return out
+}
+
+// Monitor reports each device once when it drops below Threshold, and again
+// only after it has been charged back above it.
+type Monitor struct {
+	Threshold int // Percent; 0 disables warnings
+	warned    map[string]bool
+}
+
+// Check returns the readings that newly crossed below the threshold
+func (m *Monitor) Check(readings []Reading) []Reading {
+	if m.Threshold <= 0 {
+		return nil
+	}
+	if m.warned == nil {
+		m.warned = make(map[string]bool)
+	}
+	var low []Reading
+	for _, r := range readings {
+		if r.Percent >= float64(m.Threshold) {
+			delete(m.warned, r.Device)
+			continue
+		}
+		if !m.warned[r.Device] {
+			m.warned[r.Device] = true
+			low = append(low, r)
+		}
+	}
+	return low
+}
+
+// Warning formats a low-battery message for r
+func (m *Monitor) Warning(r Reading) string {
+	return fmt.Sprintf("%s battery low: %.0f%% (below %d%%)", r.Label(), r.Percent, m.Threshold)
+}
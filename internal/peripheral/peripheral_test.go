@@ -0,0 +1,77 @@
+package peripheral
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/bluez"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
+)
+
+func TestCollectDeduplicatesBluetoothHID(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	got := Collect(
+		[]sysfs.Peripheral{{Name: "hid-aa:bb:cc:dd:ee:ff-battery", Model: "MX Master 3", Percent: 35}},
+		[]bluez.Battery{
+			{Address: "AA:BB:CC:DD:EE:FF", Name: "MX Master 3", Percent: 40},
+			{Address: "11:22:33:44:55:66", Name: "Headphones", Percent: 80},
+		},
+		now,
+	)
+	if len(got) != 2 {
+		t.Fatalf("Collect() = %+v", got)
+	}
+	if got[0].Source != SourceSysfs || got[0].Percent != 35 {
+		t.Errorf("mouse = %+v", got[0])
+	}
+	if got[1].Device != "11:22:33:44:55:66" || got[1].Source != SourceBlueZ {
+		t.Errorf("headphones = %+v", got[1])
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "peripherals.csv")}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	in := []Reading{
+		{T: now, Device: "hidpp_battery_0", Name: "Keyboard, K380", Source: SourceSysfs, Percent: 20},
+		{T: now, Device: "11:22:33:44:55:66", Name: "Headphones", Source: SourceBlueZ, Percent: 80},
+	}
+	if err := s.Append(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(in[:1]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != in[0] || got[1] != in[1] {
+		t.Errorf("Load() = %+v", got)
+	}
+	if series := Series(got); len(series) != 2 || len(series[0]) != 1 || len(series[1]) != 2 {
+		t.Errorf("Series() = %+v", series)
+	}
+}
+
+func TestMonitorWarnsOncePerDischarge(t *testing.T) {
+	m := &Monitor{Threshold: 20}
+	at := func(pct float64) []Reading {
+		return []Reading{{Device: "mouse", Percent: pct}}
+	}
+	for i, step := range []struct {
+		pct  float64
+		warn bool
+	}{
+		{50, false},
+		{19, true},
+		{15, false},
+		{90, false},
+		{10, true},
+	} {
+		if got := len(m.Check(at(step.pct))) == 1; got != step.warn {
+			t.Errorf("step %d (%.0f%%): warned = %t, want %t", i, step.pct, got, step.warn)
+		}
+	}
+}
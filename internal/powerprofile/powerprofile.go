@@ -0,0 +1,136 @@
+// Package powerprofile talks to power-profiles-daemon over D-Bus and decides
+// which platform profile the daemon's switching rules call for.
+package powerprofile
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// The low-power profile as named by ACPI and by power-profiles-daemon
+const (
+	LowPower   = "low-power"
+	PowerSaver = "power-saver"
+)
+
+// endpoint is one D-Bus name power-profiles-daemon has been published under
+type endpoint struct {
+	service string
+	path    dbus.ObjectPath
+}
+
+// endpoints lists the current name first, then the one used before 0.20
+var endpoints = []endpoint{
+	{"org.freedesktop.UPower.PowerProfiles", "/org/freedesktop/UPower/PowerProfiles"},
+	{"net.hadess.PowerProfiles", "/net/hadess/PowerProfiles"},
+}
+
+// Daemon is a running power-profiles-daemon
+type Daemon struct {
+	conn *dbus.Conn
+	ep   endpoint
+}
+
+// Find returns the power-profiles-daemon on conn, or nil if none is running
+func Find(conn *dbus.Conn) (*Daemon, error) {
+	for _, ep := range endpoints {
+		d := &Daemon{conn: conn, ep: ep}
+		if _, err := d.property("ActiveProfile"); err != nil {
+			var dbusErr dbus.Error
+			if errors.As(err, &dbusErr) && dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" {
+				continue
+			}
+			return nil, fmt.Errorf("power-profiles-daemon: %w", err)
+		}
+		return d, nil
+	}
+	return nil, nil
+}
+
+func (d *Daemon) property(name string) (dbus.Variant, error) {
+	return d.conn.Object(d.ep.service, d.ep.path).GetProperty(d.ep.service + "." + name)
+}
+
+// Active returns the active profile under its ACPI name
+func (d *Daemon) Active() (string, error) {
+	v, err := d.property("ActiveProfile")
+	if err != nil {
+		return "", fmt.Errorf("power-profiles-daemon: %w", err)
+	}
+	s, ok := v.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("power-profiles-daemon: ActiveProfile is %s", v.Signature())
+	}
+	return Normalize(s), nil
+}
+
+// Set switches the active profile; ACPI names are translated
+func (d *Daemon) Set(profile string) error {
+	if profile == LowPower {
+		profile = PowerSaver
+	}
+	obj := d.conn.Object(d.ep.service, d.ep.path)
+	err := obj.SetProperty(d.ep.service+".ActiveProfile", dbus.MakeVariant(profile))
+	if err != nil {
+		return fmt.Errorf("power-profiles-daemon: %w", err)
+	}
+	return nil
+}
+
+// Normalize maps power-profiles-daemon names onto the ACPI platform_profile
+// names so logs from either source compare
+func Normalize(profile string) string {
+	if profile == PowerSaver {
+		return LowPower
+	}
+	return profile
+}
+
+// Rules pick a profile from the power source and battery level. Empty
+// profiles leave the current one alone.
+type Rules struct {
+	OnAC       string  // Restored when plugged in
+	OnBattery  string  // Used on battery at or above LowPercent
+	Low        string  // Used on battery below LowPercent
+	LowPercent float64 // 0 disables Low
+}
+
+// Empty reports whether no rule names a profile
+func (r Rules) Empty() bool {
+	return r.OnAC == "" && r.OnBattery == "" && r.Low == ""
+}
+
+// Want returns the profile the rules call for, or "" for no preference
+func (r Rules) Want(ac bool, batt float64) string {
+	switch {
+	case ac:
+		return Normalize(r.OnAC)
+	case r.Low != "" && batt < r.LowPercent:
+		return Normalize(r.Low)
+	}
+	return Normalize(r.OnBattery)
+}
+
+// Switcher applies Rules on transitions only, so a profile picked by hand
+// stays until the power source or battery band changes
+type Switcher struct {
+	Rules Rules
+	last  string
+	init  bool
+}
+
+// Next returns the profile to switch to, if the wanted profile changed since
+// the previous call and differs from current
+func (s *Switcher) Next(ac bool, batt float64, current string) (string, bool) {
+	want := s.Rules.Want(ac, batt)
+	if s.init && want == s.last {
+		return "", false
+	}
+	s.init, s.last = true, want
+	if want == "" || want == Normalize(current) {
+		return "", false
+	}
+	return want, true
+}
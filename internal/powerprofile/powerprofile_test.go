@@ -0,0 +1,41 @@
+package powerprofile
+
+import "testing"
+
+func TestRulesWant(t *testing.T) {
+	r := Rules{OnAC: "balanced", Low: PowerSaver, LowPercent: 20}
+	cases := []struct {
+		ac   bool
+		batt float64
+		want string
+	}{
+		{true, 10, "balanced"},
+		{false, 50, ""},
+		{false, 19.5, LowPower},
+	}
+	for _, c := range cases {
+		if got := r.Want(c.ac, c.batt); got != c.want {
+			t.Errorf("Want(%v, %v) = %q, want %q", c.ac, c.batt, got, c.want)
+		}
+	}
+}
+
+func TestSwitcherTransitionsOnly(t *testing.T) {
+	s := Switcher{Rules: Rules{OnAC: "balanced", Low: LowPower, LowPercent: 20}}
+
+	if p, ok := s.Next(false, 15, "balanced"); !ok || p != LowPower {
+		t.Fatalf("first low sample = %q, %v", p, ok)
+	}
+	// Changed by hand while still low: left alone
+	if _, ok := s.Next(false, 14, "performance"); ok {
+		t.Error("switched again without a transition")
+	}
+	if p, ok := s.Next(true, 14, "performance"); !ok || p != "balanced" {
+		t.Errorf("plug-in = %q, %v", p, ok)
+	}
+	// Already on the wanted profile
+	s = Switcher{Rules: Rules{OnAC: "balanced"}}
+	if _, ok := s.Next(true, 90, "balanced"); ok {
+		t.Error("switched to the active profile")
+	}
+}
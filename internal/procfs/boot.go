@@ -0,0 +1,38 @@
+package procfs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BootID returns the random ID the kernel picks at every boot. It survives
+// suspend and hibernate, so a change between samples means a power-off or
+// reboot.
+func (s *Source) BootID() (string, bool) {
+	b, err := os.ReadFile(s.path("sys", "kernel", "random", "boot_id"))
+	if err != nil {
+		return "", false
+	}
+	id := strings.TrimSpace(string(b))
+	return id, id != ""
+}
+
+// Uptime returns the time since boot from /proc/uptime, including time
+// spent suspended
+func (s *Source) Uptime() (time.Duration, bool) {
+	b, err := os.ReadFile(s.path("uptime"))
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
@@ -0,0 +1,87 @@
+package procfs
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inputDevices are substrings of /proc/interrupts action names that belong
+// to keyboards, touchpads and mice: the PS/2 controller plus the ACPI IDs
+// of common I2C-HID touchpad vendors.
+var inputDevices = []string{
+	"i8042", "hid", "elan", "syna", "alps", "gxtp", "msft", "atml", "touchpad", "keyboard", "mouse",
+}
+
+func isInputLine(actions string) bool {
+	actions = strings.ToLower(actions)
+	for _, d := range inputDevices {
+		if strings.Contains(actions, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// InputInterrupts returns the total interrupt count of input devices across
+// all CPUs. ok is false if /proc/interrupts is unreadable or lists no input
+// device, e.g. on machines whose only input is USB.
+func (s *Source) InputInterrupts() (total uint64, ok bool) {
+	f, err := os.Open(s.path("interrupts"))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return 0, false
+	}
+	cpus := len(strings.Fields(sc.Text()))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		// IRQ label, one count per CPU, then chip, hwirq/type and actions
+		if len(fields) <= cpus+1 || !isInputLine(strings.Join(fields[cpus+1:], " ")) {
+			continue
+		}
+		for _, f := range fields[1 : cpus+1] {
+			n, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				break
+			}
+			total += n
+		}
+		ok = true
+	}
+	return total, ok
+}
+
+// InputTracker turns periodic input interrupt counts into the time since
+// the last keystroke, touch or mouse movement.
+type InputTracker struct {
+	count   uint64
+	changed time.Time // When count last moved
+}
+
+// Observe records the interrupt count sampled at t. The first observation
+// counts as activity since there is nothing to compare it against.
+func (tr *InputTracker) Observe(count uint64, t time.Time) {
+	if tr.changed.IsZero() || count != tr.count {
+		tr.count, tr.changed = count, t
+	}
+}
+
+// IdleSecs returns the seconds since input was last seen, or -1 before the
+// first observation
+func (tr *InputTracker) IdleSecs(now time.Time) int {
+	if tr.changed.IsZero() {
+		return -1
+	}
+	idle := int(now.Sub(tr.changed).Seconds())
+	if idle < 0 {
+		return 0
+	}
+	return idle
+}
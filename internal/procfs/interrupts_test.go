@@ -0,0 +1,62 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const interrupts = `           CPU0       CPU1       CPU2       CPU3
+  0:          8          0          0          0  IR-IO-APIC    2-edge      timer
+  1:        100         20          0          5  IR-IO-APIC    1-edge      i8042
+  9:          0        512          0          0  IR-IO-APIC    9-fasteoi   acpi
+ 12:          3          0          1          0  IR-IO-APIC   12-edge      i8042
+ 51:          0          0       4000         10  intel-gpio   17  ELAN0672:00
+128:      99999          0          0          0  IR-PCI-MSI 327680-edge      xhci_hcd
+NMI:          0          0          0          0   Non-maskable interrupts
+LOC:     123456     234567     345678     456789   Local timer interrupts
+`
+
+func writeProc(t *testing.T, name, content string) *Source {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewSource(root)
+}
+
+func TestInputInterrupts(t *testing.T) {
+	total, ok := writeProc(t, "interrupts", interrupts).InputInterrupts()
+	if !ok || total != 125+4+4010 {
+		t.Errorf("InputInterrupts() = %d, %t; want %d, true", total, ok, 125+4+4010)
+	}
+}
+
+func TestInputInterruptsWithoutInputDevices(t *testing.T) {
+	src := writeProc(t, "interrupts", "           CPU0\n  0:   8   IO-APIC   2-edge   timer\n")
+	if _, ok := src.InputInterrupts(); ok {
+		t.Error("InputInterrupts() reported input devices")
+	}
+	if _, ok := NewSource(t.TempDir()).InputInterrupts(); ok {
+		t.Error("InputInterrupts() succeeded without /proc/interrupts")
+	}
+}
+
+func TestInputTracker(t *testing.T) {
+	var tr InputTracker
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := tr.IdleSecs(t0); got != -1 {
+		t.Errorf("IdleSecs() before Observe = %d, want -1", got)
+	}
+	tr.Observe(100, t0)
+	tr.Observe(100, t0.Add(30*time.Second))
+	if got := tr.IdleSecs(t0.Add(45 * time.Second)); got != 45 {
+		t.Errorf("IdleSecs() with no input = %d, want 45", got)
+	}
+	tr.Observe(150, t0.Add(60*time.Second))
+	if got := tr.IdleSecs(t0.Add(65 * time.Second)); got != 5 {
+		t.Errorf("IdleSecs() after input = %d, want 5", got)
+	}
+}
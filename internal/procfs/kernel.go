@@ -0,0 +1,16 @@
+package procfs
+
+import (
+	"os"
+	"strings"
+)
+
+// KernelRelease returns the running kernel's release, as printed by uname -r
+func (s *Source) KernelRelease() (string, bool) {
+	b, err := os.ReadFile(s.path("sys", "kernel", "osrelease"))
+	if err != nil {
+		return "", false
+	}
+	v := strings.TrimSpace(string(b))
+	return v, v != ""
+}
@@ -0,0 +1,31 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LidOpen reports whether the laptop lid is open, from the ACPI button
+// state ("state:      open"). known is false on machines without a lid
+// switch; with several, any open lid counts.
+func (s *Source) LidOpen() (open, known bool) {
+	files, _ := filepath.Glob(s.path("acpi", "button", "lid", "*", "state"))
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		_, state, ok := strings.Cut(string(b), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(state) {
+		case "open":
+			open, known = true, true
+		case "closed":
+			known = true
+		}
+	}
+	return open, known
+}
@@ -0,0 +1,27 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLidOpen(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "acpi", "button", "lid", "LID0")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := NewSource(root)
+	if _, known := src.LidOpen(); known {
+		t.Error("LidOpen() known without a state file")
+	}
+	for state, want := range map[string]bool{"open": true, "closed": false} {
+		if err := os.WriteFile(filepath.Join(dir, "state"), []byte("state:      "+state+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if open, known := src.LidOpen(); !known || open != want {
+			t.Errorf("LidOpen() with %s = %t, %t", state, open, known)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package procfs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ClockTicks is USER_HZ, the unit of /proc/<pid>/stat CPU times. The kernel
+// fixes it at 100 for userspace on every Linux architecture.
+const ClockTicks = 100
+
+// ProcStat is the CPU usage of one process from /proc/<pid>/stat
+type ProcStat struct {
+	PID   int
+	Name  string // comm, at most 15 characters
+	Start uint64 // starttime in clock ticks since boot, to detect PID reuse
+	Ticks uint64 // utime + stime
+}
+
+// parseProcStat parses a /proc/<pid>/stat line. comm may itself contain
+// spaces and parentheses, so it runs up to the last ')'.
+func parseProcStat(line string) (ProcStat, bool) {
+	open := strings.IndexByte(line, '(')
+	end := strings.LastIndexByte(line, ')')
+	if open < 0 || end < open {
+		return ProcStat{}, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line[:open]))
+	if err != nil {
+		return ProcStat{}, false
+	}
+	// Fields after comm start at field 3 (state); utime and stime are fields
+	// 14 and 15, starttime is 22
+	rest := strings.Fields(line[end+1:])
+	if len(rest) < 20 {
+		return ProcStat{}, false
+	}
+	utime, err1 := strconv.ParseUint(rest[11], 10, 64)
+	stime, err2 := strconv.ParseUint(rest[12], 10, 64)
+	start, err3 := strconv.ParseUint(rest[19], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ProcStat{}, false
+	}
+	return ProcStat{PID: pid, Name: line[open+1 : end], Start: start, Ticks: utime + stime}, true
+}
+
+// Processes reads the CPU times of every running process. Processes that
+// exit while the table is being read are skipped.
+func (s *Source) Processes() ([]ProcStat, error) {
+	entries, err := os.ReadDir(s.Root)
+	if err != nil {
+		return nil, err
+	}
+	var out []ProcStat
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		b, err := os.ReadFile(s.path(e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		if p, ok := parseProcStat(string(b)); ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+type procKey struct {
+	pid   int
+	start uint64
+}
+
+// ProcessTracker turns successive process tables into CPU time per process
+// name over each interval
+type ProcessTracker struct {
+	prev map[procKey]uint64
+}
+
+// Update records the process table and returns the CPU seconds each process
+// name used since the previous call, or ok=false on the first call. A
+// process started during the interval is charged its whole CPU time;
+// processes that started and exited between calls are not seen at all.
+func (tr *ProcessTracker) Update(procs []ProcStat) (cpu map[string]float64, ok bool) {
+	cur := make(map[procKey]uint64, len(procs))
+	for _, p := range procs {
+		cur[procKey{p.PID, p.Start}] = p.Ticks
+	}
+	prev := tr.prev
+	tr.prev = cur
+	if prev == nil {
+		return nil, false
+	}
+
+	cpu = make(map[string]float64)
+	for _, p := range procs {
+		ticks := p.Ticks
+		if before, seen := prev[procKey{p.PID, p.Start}]; seen {
+			if before > ticks {
+				continue
+			}
+			ticks -= before
+		}
+		if ticks > 0 {
+			cpu[p.Name] += float64(ticks) / ClockTicks
+		}
+	}
+	return cpu, true
+}
@@ -0,0 +1,62 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func statLine(pid, comm string, utime, stime, start string) string {
+	return pid + " (" + comm + ") S 1 1 1 0 -1 4194560 100 0 0 0 " + utime + " " + stime + " 0 0 20 0 1 0 " + start + " 1000 100\n"
+}
+
+func TestParseProcStat(t *testing.T) {
+	p, ok := parseProcStat(statLine("4242", "Web Content (x)", "300", "50", "12345"))
+	if !ok || p.PID != 4242 || p.Name != "Web Content (x)" || p.Ticks != 350 || p.Start != 12345 {
+		t.Errorf("parseProcStat() = %+v, %t", p, ok)
+	}
+}
+
+func TestProcesses(t *testing.T) {
+	root := t.TempDir()
+	for pid, line := range map[string]string{
+		"1":   statLine("1", "systemd", "10", "5", "1"),
+		"200": statLine("200", "firefox", "1000", "200", "500"),
+	} {
+		if err := os.MkdirAll(filepath.Join(root, pid), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, pid, "stat"), []byte(line), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "sys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	procs, err := NewSource(root).Processes()
+	if err != nil || len(procs) != 2 {
+		t.Fatalf("Processes() = %+v, %v", procs, err)
+	}
+}
+
+func TestProcessTracker(t *testing.T) {
+	var tr ProcessTracker
+	if _, ok := tr.Update([]ProcStat{
+		{PID: 10, Name: "firefox", Start: 1, Ticks: 1000},
+		{PID: 11, Name: "firefox", Start: 1, Ticks: 500},
+		{PID: 20, Name: "make", Start: 5, Ticks: 100},
+	}); ok {
+		t.Error("first Update() reported usage")
+	}
+	cpu, ok := tr.Update([]ProcStat{
+		{PID: 10, Name: "firefox", Start: 1, Ticks: 1200},
+		{PID: 11, Name: "firefox", Start: 1, Ticks: 550},
+		{PID: 20, Name: "cc1plus", Start: 9, Ticks: 300}, // PID reused by a new process
+	})
+	if !ok {
+		t.Fatal("second Update() = !ok")
+	}
+	if cpu["firefox"] != 2.5 || cpu["cc1plus"] != 3 || cpu["make"] != 0 {
+		t.Errorf("Update() = %v", cpu)
+	}
+}
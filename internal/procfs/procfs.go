@@ -0,0 +1,27 @@
+// Package procfs reads process and kernel statistics from /proc.
+package procfs
+
+import (
+	"path/filepath"
+)
+
+// DefaultRoot is where procfs is normally mounted
+const DefaultRoot = "/proc"
+
+// Source reads from a procfs tree rooted at Root, so tests can point it at
+// a captured copy.
+type Source struct {
+	Root string
+}
+
+// NewSource returns a Source for root, or for DefaultRoot if root is empty
+func NewSource(root string) *Source {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Source{Root: root}
+}
+
+func (s *Source) path(elem ...string) string {
+	return filepath.Join(append([]string{s.Root}, elem...)...)
+}
@@ -0,0 +1,91 @@
+package procfs
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CPUTimes holds the aggregate jiffy counters from the "cpu" line of
+// /proc/stat
+type CPUTimes struct {
+	Busy  uint64
+	Total uint64
+}
+
+// CPUTimes reads the aggregate CPU counters. Idle and iowait count as idle;
+// guest time is already included in user and nice, so it is skipped.
+func (s *Source) CPUTimes() (CPUTimes, bool) {
+	f, err := os.Open(s.path("stat"))
+	if err != nil {
+		return CPUTimes{}, false
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var t CPUTimes
+		// user nice system idle iowait irq softirq steal guest guest_nice
+		for i, f := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			n, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				return CPUTimes{}, false
+			}
+			t.Total += n
+			if i != 3 && i != 4 {
+				t.Busy += n
+			}
+		}
+		return t, true
+	}
+	return CPUTimes{}, false
+}
+
+// Utilisation returns the busy percentage between two readings, or NaN if
+// no time passed between them
+func Utilisation(prev, cur CPUTimes) float64 {
+	if cur.Total <= prev.Total || cur.Busy < prev.Busy {
+		return math.NaN()
+	}
+	return float64(cur.Busy-prev.Busy) / float64(cur.Total-prev.Total) * 100
+}
+
+// CPUTracker reports utilisation since the previous reading
+type CPUTracker struct {
+	prev CPUTimes
+	ok   bool
+}
+
+// Update records cur and returns the utilisation since the last call, NaN
+// on the first call
+func (tr *CPUTracker) Update(cur CPUTimes) float64 {
+	util := math.NaN()
+	if tr.ok {
+		util = Utilisation(tr.prev, cur)
+	}
+	tr.prev, tr.ok = cur, true
+	return util
+}
+
+// LoadAvg returns the 1-minute load average
+func (s *Source) LoadAvg() (float64, bool) {
+	b, err := os.ReadFile(s.path("loadavg"))
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	return v, err == nil
+}
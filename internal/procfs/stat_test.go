@@ -0,0 +1,42 @@
+package procfs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCPUTimes(t *testing.T) {
+	src := writeProc(t, "stat", "cpu  100 0 50 800 50 0 0 0 0 0\ncpu0 50 0 25 400 25 0 0 0 0 0\nintr 12345\n")
+	got, ok := src.CPUTimes()
+	if !ok || got.Busy != 150 || got.Total != 1000 {
+		t.Errorf("CPUTimes() = %+v, %t; want busy 150 of 1000", got, ok)
+	}
+}
+
+func TestCPUTracker(t *testing.T) {
+	var tr CPUTracker
+	if u := tr.Update(CPUTimes{Busy: 150, Total: 1000}); !math.IsNaN(u) {
+		t.Errorf("first Update() = %v, want NaN", u)
+	}
+	if u := tr.Update(CPUTimes{Busy: 400, Total: 2000}); u != 25 {
+		t.Errorf("Update() = %v, want 25", u)
+	}
+	if u := tr.Update(CPUTimes{Busy: 400, Total: 2000}); !math.IsNaN(u) {
+		t.Errorf("Update() without elapsed time = %v, want NaN", u)
+	}
+}
+
+func TestLoadAvg(t *testing.T) {
+	v, ok := writeProc(t, "loadavg", "1.25 0.80 0.50 2/612 4242\n").LoadAvg()
+	if !ok || v != 1.25 {
+		t.Errorf("LoadAvg() = %v, %t", v, ok)
+	}
+}
+
+func TestUptime(t *testing.T) {
+	d, ok := writeProc(t, "uptime", "3725.50 14000.00\n").Uptime()
+	if !ok || d != 3725500*time.Millisecond {
+		t.Errorf("Uptime() = %v, %t", d, ok)
+	}
+}
@@ -0,0 +1,224 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Threshold attribute names, current ABI first, then the names older
+// thinkpad_acpi kernels used.
+var (
+	startThresholdAttrs = []string{"charge_control_start_threshold", "charge_start_threshold"}
+	endThresholdAttrs   = []string{"charge_control_end_threshold", "charge_stop_threshold"}
+)
+
+// ChargeControl is the firmware charging configuration of one pack.
+// Start and End are -1 when the pack does not expose them.
+type ChargeControl struct {
+	Pack       string
+	Start      int
+	End        int
+	Behaviour  string   // Active charge_behaviour, empty if unsupported
+	Behaviours []string // Values the kernel accepts for charge_behaviour
+	dir        string
+}
+
+// HasThresholds reports whether the pack exposes an end threshold
+func (c ChargeControl) HasThresholds() bool {
+	return c.End >= 0
+}
+
+// parseChoices splits a sysfs choice list such as
+// "[auto] inhibit-charge force-discharge" into the available values and
+// the bracketed active one.
+func parseChoices(s string) (active string, available []string) {
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(f, "[") && strings.HasSuffix(f, "]") {
+			f = strings.Trim(f, "[]")
+			active = f
+		}
+		available = append(available, f)
+	}
+	return active, available
+}
+
+func readAttr(dir string, names []string) (int, string) {
+	for _, name := range names {
+		if v, ok := readInt(dir, name); ok {
+			return int(v), name
+		}
+	}
+	return -1, ""
+}
+
+// ChargeControls returns the charge settings of every system pack
+func (s *Source) ChargeControls() []ChargeControl {
+	var out []ChargeControl
+	for _, dir := range s.batteryDirs() {
+		c := ChargeControl{Pack: filepath.Base(dir), dir: dir}
+		c.Start, _ = readAttr(dir, startThresholdAttrs)
+		c.End, _ = readAttr(dir, endThresholdAttrs)
+		if v, ok := readValue(dir, "charge_behaviour"); ok {
+			c.Behaviour, c.Behaviours = parseChoices(v)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// ChargeEndThreshold returns the lowest end threshold across packs, i.e. the
+// level at which firmware stops charging.
+func (s *Source) ChargeEndThreshold() (int, bool) {
+	end, found := 100, false
+	for _, c := range s.ChargeControls() {
+		if c.HasThresholds() && c.End < end {
+			end, found = c.End, true
+		}
+	}
+	return end, found
+}
+
+// ValidateThresholds checks a start/end pair against the range the kernel
+// accepts: 0 <= start < end <= 100.
+func ValidateThresholds(start, end int) error {
+	if start < 0 || start > 100 || end < 0 || end > 100 {
+		return fmt.Errorf("thresholds must be between 0 and 100 (got %d-%d)", start, end)
+	}
+	if start >= end {
+		return fmt.Errorf("start threshold %d must be below end threshold %d", start, end)
+	}
+	return nil
+}
+
+func writeAttr(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("%s: permission denied (run as root or grant write access via udev)", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// SetChargeThresholds writes start and end to every pack that supports
+// thresholds. Packs without a start threshold only get the end value.
+func (s *Source) SetChargeThresholds(start, end int) error {
+	if err := ValidateThresholds(start, end); err != nil {
+		return err
+	}
+	applied := 0
+	for _, c := range s.ChargeControls() {
+		if !c.HasThresholds() {
+			continue
+		}
+		_, startAttr := readAttr(c.dir, startThresholdAttrs)
+		_, endAttr := readAttr(c.dir, endThresholdAttrs)
+
+		// The kernel rejects start >= end, so order the writes so the pair is
+		// valid after each step.
+		writes := [][2]string{{startAttr, strconv.Itoa(start)}, {endAttr, strconv.Itoa(end)}}
+		if c.End >= 0 && start >= c.End {
+			writes[0], writes[1] = writes[1], writes[0]
+		}
+		for _, w := range writes {
+			if w[0] == "" {
+				continue
+			}
+			if err := writeAttr(c.dir, w[0], w[1]); err != nil {
+				return fmt.Errorf("%s: %w", c.Pack, err)
+			}
+		}
+		applied++
+	}
+	if applied == 0 {
+		return fmt.Errorf("no battery exposes charge_control_end_threshold")
+	}
+	return nil
+}
+
+// ApplyChargeThresholds brings every pack that supports thresholds to the
+// configured values, where a negative start or end leaves that threshold as
+// the firmware has it. Only values that differ are written, and every pack
+// is checked before any is written: the pair a pack would end up with must
+// have start below end. It reports whether anything was written.
+func (s *Source) ApplyChargeThresholds(start, end int) (bool, error) {
+	if start >= 0 && end >= 0 && start >= end {
+		return false, fmt.Errorf("start threshold %d must be below end threshold %d", start, end)
+	}
+	type plan struct {
+		control ChargeControl
+		writes  [][2]string
+	}
+	var plans []plan
+	for _, c := range s.ChargeControls() {
+		if !c.HasThresholds() {
+			continue
+		}
+		_, startAttr := readAttr(c.dir, startThresholdAttrs)
+		_, endAttr := readAttr(c.dir, endThresholdAttrs)
+		p := plan{control: c}
+		newStart, newEnd := c.Start, c.End
+		if start >= 0 && startAttr != "" && start != c.Start {
+			newStart = start
+			p.writes = append(p.writes, [2]string{startAttr, strconv.Itoa(start)})
+		}
+		if end >= 0 && end != c.End {
+			newEnd = end
+			p.writes = append(p.writes, [2]string{endAttr, strconv.Itoa(end)})
+		}
+		if len(p.writes) == 0 {
+			continue
+		}
+		if newStart >= 0 && newStart >= newEnd {
+			return false, fmt.Errorf("%s: start threshold %d must be below end threshold %d", c.Pack, newStart, newEnd)
+		}
+		// As in SetChargeThresholds, raise end before start when the new
+		// start would not be below the current end
+		if len(p.writes) == 2 && newStart >= c.End {
+			p.writes[0], p.writes[1] = p.writes[1], p.writes[0]
+		}
+		plans = append(plans, p)
+	}
+
+	for _, p := range plans {
+		for _, w := range p.writes {
+			if err := writeAttr(p.control.dir, w[0], w[1]); err != nil {
+				return true, fmt.Errorf("%s: %w", p.control.Pack, err)
+			}
+		}
+	}
+	return len(plans) > 0, nil
+}
+
+// SetChargeBehaviour writes charge_behaviour on every pack that supports
+// it, rejecting values the kernel does not list as available.
+func (s *Source) SetChargeBehaviour(behaviour string) error {
+	applied := 0
+	for _, c := range s.ChargeControls() {
+		if len(c.Behaviours) == 0 {
+			continue
+		}
+		valid := false
+		for _, b := range c.Behaviours {
+			if b == behaviour {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%s: charge_behaviour %q not supported (available: %s)",
+				c.Pack, behaviour, strings.Join(c.Behaviours, ", "))
+		}
+		if err := writeAttr(c.dir, "charge_behaviour", behaviour); err != nil {
+			return fmt.Errorf("%s: %w", c.Pack, err)
+		}
+		applied++
+	}
+	if applied == 0 {
+		return fmt.Errorf("no battery exposes charge_behaviour")
+	}
+	return nil
+}
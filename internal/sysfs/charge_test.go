@@ -0,0 +1,145 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, src *Source, rel string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(src.powerSupplyDir(), rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func TestChargeControls(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":                           "Battery",
+		"BAT0/charge_control_start_threshold": "40",
+		"BAT0/charge_control_end_threshold":   "80",
+		"BAT0/charge_behaviour":               "[auto] inhibit-charge force-discharge",
+		"BAT1/type":                           "Battery",
+		"BAT1/charge_stop_threshold":          "90", // legacy thinkpad_acpi name
+	})
+	controls := src.ChargeControls()
+	if len(controls) != 2 {
+		t.Fatalf("ChargeControls() = %+v", controls)
+	}
+	c := controls[0]
+	if c.Start != 40 || c.End != 80 || c.Behaviour != "auto" || len(c.Behaviours) != 3 {
+		t.Errorf("BAT0 = %+v", c)
+	}
+	if c := controls[1]; c.Start != -1 || c.End != 90 || len(c.Behaviours) != 0 {
+		t.Errorf("BAT1 = %+v", c)
+	}
+	if end, ok := src.ChargeEndThreshold(); !ok || end != 80 {
+		t.Errorf("ChargeEndThreshold() = %d, %t; want 80, true", end, ok)
+	}
+}
+
+func TestValidateThresholds(t *testing.T) {
+	for _, tt := range []struct {
+		start, end int
+		ok         bool
+	}{
+		{40, 80, true},
+		{0, 100, true},
+		{80, 80, false},
+		{90, 80, false},
+		{-1, 80, false},
+		{40, 101, false},
+	} {
+		if err := ValidateThresholds(tt.start, tt.end); (err == nil) != tt.ok {
+			t.Errorf("ValidateThresholds(%d, %d) = %v", tt.start, tt.end, err)
+		}
+	}
+}
+
+func TestSetChargeThresholds(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":                           "Battery",
+		"BAT0/charge_control_start_threshold": "40",
+		"BAT0/charge_control_end_threshold":   "60",
+	})
+	// Raising start above the current end must still succeed
+	if err := src.SetChargeThresholds(75, 90); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFixture(t, src, "BAT0/charge_control_start_threshold"); got != "75" {
+		t.Errorf("start = %s, want 75", got)
+	}
+	if got := readFixture(t, src, "BAT0/charge_control_end_threshold"); got != "90" {
+		t.Errorf("end = %s, want 90", got)
+	}
+
+	if err := src.SetChargeThresholds(90, 80); err == nil {
+		t.Error("SetChargeThresholds(90, 80) succeeded")
+	}
+}
+
+func TestSetChargeThresholdsUnsupported(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":     "Battery",
+		"BAT0/capacity": "50",
+	})
+	if err := src.SetChargeThresholds(40, 80); err == nil {
+		t.Error("SetChargeThresholds() succeeded without threshold attributes")
+	}
+}
+
+func TestSetChargeBehaviour(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":             "Battery",
+		"BAT0/charge_behaviour": "[auto] inhibit-charge",
+	})
+	if err := src.SetChargeBehaviour("force-discharge"); err == nil {
+		t.Error("SetChargeBehaviour() accepted a value the kernel does not list")
+	}
+	if err := src.SetChargeBehaviour("inhibit-charge"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFixture(t, src, "BAT0/charge_behaviour"); got != "inhibit-charge" {
+		t.Errorf("charge_behaviour = %q", got)
+	}
+}
+
+func TestApplyChargeThresholds(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":                           "Battery",
+		"BAT0/charge_control_start_threshold": "40",
+		"BAT0/charge_control_end_threshold":   "80",
+	})
+
+	// -1 leaves start alone; end already matches, so nothing is written
+	if changed, err := src.ApplyChargeThresholds(-1, 80); err != nil || changed {
+		t.Fatalf("ApplyChargeThresholds(-1, 80) = %v, %v", changed, err)
+	}
+	if changed, err := src.ApplyChargeThresholds(40, 80); err != nil || changed {
+		t.Fatalf("ApplyChargeThresholds(40, 80) = %v, %v", changed, err)
+	}
+
+	if changed, err := src.ApplyChargeThresholds(-1, 90); err != nil || !changed {
+		t.Fatalf("ApplyChargeThresholds(-1, 90) = %v, %v", changed, err)
+	}
+	if got := readFixture(t, src, "BAT0/charge_control_start_threshold"); got != "40" {
+		t.Errorf("start = %s, want 40 (unchanged)", got)
+	}
+	if got := readFixture(t, src, "BAT0/charge_control_end_threshold"); got != "90" {
+		t.Errorf("end = %s, want 90", got)
+	}
+
+	// Invalid pairs, configured or resulting, write nothing
+	if _, err := src.ApplyChargeThresholds(85, 60); err == nil {
+		t.Error("ApplyChargeThresholds(85, 60) succeeded")
+	}
+	if _, err := src.ApplyChargeThresholds(95, -1); err == nil {
+		t.Error("ApplyChargeThresholds(95, -1) succeeded above the firmware end of 90")
+	}
+	if got := readFixture(t, src, "BAT0/charge_control_start_threshold"); got != "40" {
+		t.Errorf("start = %s after invalid pairs, want 40", got)
+	}
+}
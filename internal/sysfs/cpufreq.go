@@ -0,0 +1,20 @@
+package sysfs
+
+import "path/filepath"
+
+// CPUFreqMHz returns the mean scaling_cur_freq across CPUs in MHz
+func (s *Source) CPUFreqMHz() (float64, bool) {
+	dirs, _ := filepath.Glob(filepath.Join(s.Root, "devices", "system", "cpu", "cpu[0-9]*", "cpufreq"))
+	var sum float64
+	n := 0
+	for _, dir := range dirs {
+		if khz, ok := readInt(dir, "scaling_cur_freq"); ok {
+			sum += float64(khz) / 1000
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
@@ -0,0 +1,107 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Connector is a DRM display output such as card0-eDP-1 or card0-HDMI-A-1
+type Connector struct {
+	Name      string
+	Internal  bool // Built-in panel (eDP, LVDS, DSI)
+	Connected bool // A display is attached
+	Enabled   bool // The output is driven by a CRTC
+	DPMSOn    bool
+}
+
+// Active reports whether the connector is showing an image as far as DRM knows
+func (c Connector) Active() bool {
+	return c.Connected && c.Enabled && c.DPMSOn
+}
+
+var internalPanels = []string{"-eDP-", "-LVDS-", "-DSI-"}
+
+// Connectors returns every DRM connector in name order. Entries without a
+// status attribute (cards, render nodes) are skipped.
+func (s *Source) Connectors() []Connector {
+	drm := filepath.Join(s.Root, "class", "drm")
+	entries, err := os.ReadDir(drm)
+	if err != nil {
+		return nil
+	}
+	var out []Connector
+	for _, e := range entries {
+		dir := filepath.Join(drm, e.Name())
+		status, ok := readValue(dir, "status")
+		if !ok {
+			continue
+		}
+		c := Connector{Name: e.Name(), Connected: status == "connected"}
+		for _, p := range internalPanels {
+			if strings.Contains(c.Name, p) {
+				c.Internal = true
+			}
+		}
+		enabled, _ := readValue(dir, "enabled")
+		c.Enabled = enabled == "enabled"
+		dpms, _ := readValue(dir, "dpms")
+		c.DPMSOn = dpms == "On"
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ExternalDisplays counts connected outputs other than the built-in panel.
+// known is false when DRM exposes no connectors.
+func (s *Source) ExternalDisplays() (n int, known bool) {
+	conns := s.Connectors()
+	for _, c := range conns {
+		if c.Connected && !c.Internal {
+			n++
+		}
+	}
+	return n, len(conns) > 0
+}
+
+// backlightOn reports whether any backlight is powered with non-zero
+// brightness. known is false when the machine has no backlight device.
+func (s *Source) backlightOn() (on, known bool) {
+	dirs, _ := filepath.Glob(filepath.Join(s.Root, "class", "backlight", "*"))
+	for _, dir := range dirs {
+		brightness, ok := readInt(dir, "brightness")
+		if !ok {
+			continue
+		}
+		known = true
+		// bl_power follows FB_BLANK_*: 0 is unblanked, anything else is off
+		power, ok := readInt(dir, "bl_power")
+		if brightness > 0 && (!ok || power == 0) {
+			on = true
+		}
+	}
+	return on, known
+}
+
+// ScreenOn reports whether any display is lit. An external connector counts
+// when DRM drives it; the built-in panel also needs its backlight on, since
+// compositors often blank it through the backlight alone. Without DRM
+// connectors the backlight decides. known is false when neither is exposed.
+func (s *Source) ScreenOn() (on, known bool) {
+	blOn, blKnown := s.backlightOn()
+	connectors := s.Connectors()
+	for _, c := range connectors {
+		if !c.Active() {
+			continue
+		}
+		if !c.Internal || !blKnown || blOn {
+			return true, true
+		}
+	}
+	if len(connectors) > 0 {
+		return false, true
+	}
+	return blOn, blKnown
+}
@@ -0,0 +1,80 @@
+package sysfs
+
+import "testing"
+
+func TestScreenOn(t *testing.T) {
+	panel := func(dpms, brightness, blPower string) map[string]string {
+		return map[string]string{
+			"class/drm/card0/dev":                        "226:0",
+			"class/drm/card0-eDP-1/status":               "connected",
+			"class/drm/card0-eDP-1/enabled":              "enabled",
+			"class/drm/card0-eDP-1/dpms":                 dpms,
+			"class/drm/card0-HDMI-A-1/status":            "disconnected",
+			"class/drm/card0-HDMI-A-1/enabled":           "disabled",
+			"class/drm/card0-HDMI-A-1/dpms":              "Off",
+			"class/backlight/intel_backlight/brightness": brightness,
+			"class/backlight/intel_backlight/bl_power":   blPower,
+		}
+	}
+	for _, tt := range []struct {
+		name      string
+		files     map[string]string
+		on, known bool
+	}{
+		{"panel lit", panel("On", "4000", "0"), true, true},
+		{"dpms off", panel("Off", "4000", "0"), false, true},
+		{"backlight at zero", panel("On", "0", "0"), false, true},
+		{"backlight blanked", panel("On", "4000", "4"), false, true},
+		{"external monitor with lid closed", map[string]string{
+			"class/drm/card0-eDP-1/status":           "connected",
+			"class/drm/card0-eDP-1/enabled":          "disabled",
+			"class/drm/card0-eDP-1/dpms":             "Off",
+			"class/drm/card0-DP-2/status":            "connected",
+			"class/drm/card0-DP-2/enabled":           "enabled",
+			"class/drm/card0-DP-2/dpms":              "On",
+			"class/backlight/acpi_video0/brightness": "0",
+		}, true, true},
+		{"backlight only", map[string]string{
+			"class/backlight/acpi_video0/brightness": "7",
+		}, true, true},
+		{"nothing exposed", map[string]string{
+			"class/power_supply/BAT0/type": "Battery",
+		}, false, false},
+	} {
+		on, known := rootFixture(t, tt.files).ScreenOn()
+		if on != tt.on || known != tt.known {
+			t.Errorf("%s: ScreenOn() = %t, %t; want %t, %t", tt.name, on, known, tt.on, tt.known)
+		}
+	}
+}
+
+func TestConnectors(t *testing.T) {
+	src := rootFixture(t, map[string]string{
+		"class/drm/card0/dev":             "226:0",
+		"class/drm/renderD128/dev":        "226:128",
+		"class/drm/card0-eDP-1/status":    "connected",
+		"class/drm/card0-HDMI-A-1/status": "disconnected",
+	})
+	got := src.Connectors()
+	if len(got) != 2 {
+		t.Fatalf("Connectors() = %+v", got)
+	}
+	if !got[1].Internal || !got[1].Connected || got[0].Internal || got[0].Connected {
+		t.Errorf("Connectors() = %+v", got)
+	}
+}
+
+func TestExternalDisplays(t *testing.T) {
+	src := rootFixture(t, map[string]string{
+		"class/drm/card0-eDP-1/status":    "connected",
+		"class/drm/card0-HDMI-A-1/status": "connected",
+		"class/drm/card0-DP-1/status":     "connected",
+		"class/drm/card0-DP-2/status":     "disconnected",
+	})
+	if n, known := src.ExternalDisplays(); n != 2 || !known {
+		t.Errorf("ExternalDisplays() = %d, %t; want 2, true", n, known)
+	}
+	if _, known := rootFixture(t, nil).ExternalDisplays(); known {
+		t.Error("ExternalDisplays() known without DRM")
+	}
+}
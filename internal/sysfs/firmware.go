@@ -0,0 +1,38 @@
+package sysfs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// BIOSVersion returns the DMI BIOS version, e.g. N32ET91W (1.67 )
+func (s *Source) BIOSVersion() (string, bool) {
+	v, ok := readValue(filepath.Join(s.Root, "class", "dmi", "id"), "bios_version")
+	return v, ok && v != ""
+}
+
+// BatteryModel identifies the cell pack behind one battery
+type BatteryModel struct {
+	Pack         string
+	Manufacturer string
+	Model        string
+}
+
+func (m BatteryModel) String() string {
+	return strings.TrimSpace(m.Manufacturer + " " + m.Model)
+}
+
+// BatteryModels returns the manufacturer and model_name of every system
+// pack that reports either
+func (s *Source) BatteryModels() []BatteryModel {
+	var out []BatteryModel
+	for _, dir := range s.batteryDirs() {
+		m := BatteryModel{Pack: filepath.Base(dir)}
+		m.Manufacturer, _ = readValue(dir, "manufacturer")
+		m.Model, _ = readValue(dir, "model_name")
+		if m.String() != "" {
+			out = append(out, m)
+		}
+	}
+	return out
+}
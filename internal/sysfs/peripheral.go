@@ -0,0 +1,60 @@
+package sysfs
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Peripheral is a device battery (mouse, keyboard, headset) that the
+// kernel exposes with scope=Device.
+type Peripheral struct {
+	Name          string // power_supply entry, e.g. hid-aa:bb:cc:dd:ee:ff-battery
+	Model         string // model_name, empty if not reported
+	Percent       float64
+	CapacityLevel string
+}
+
+// levelPercent approximates a charge level for devices that only report
+// capacity_level.
+var levelPercent = map[string]float64{
+	"Full":     100,
+	"High":     80,
+	"Normal":   50,
+	"Low":      20,
+	"Critical": 5,
+}
+
+// Peripherals returns the device batteries that report a level, in name order
+func (s *Source) Peripherals() []Peripheral {
+	entries, err := os.ReadDir(s.powerSupplyDir())
+	if err != nil {
+		return nil
+	}
+	var out []Peripheral
+	for _, e := range entries {
+		dir := filepath.Join(s.powerSupplyDir(), e.Name())
+		if t, _ := readValue(dir, "type"); t != "Battery" {
+			continue
+		}
+		if scope, _ := readValue(dir, "scope"); scope != "Device" {
+			continue
+		}
+		p := Peripheral{Name: e.Name(), Percent: math.NaN()}
+		p.Model, _ = readValue(dir, "model_name")
+		p.CapacityLevel, _ = readValue(dir, "capacity_level")
+		if v, ok := readInt(dir, "capacity"); ok {
+			p.Percent = float64(v)
+		} else if v, ok := levelPercent[p.CapacityLevel]; ok {
+			p.Percent = v
+		}
+		// Disconnected HID devices linger with no level at all
+		if math.IsNaN(p.Percent) {
+			continue
+		}
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,38 @@
+package sysfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func (s *Source) acpiDir() string {
+	return filepath.Join(s.Root, "firmware", "acpi")
+}
+
+// PlatformProfile returns the active ACPI platform profile, e.g. low-power,
+// balanced or performance, and the profiles the firmware offers
+func (s *Source) PlatformProfile() (active string, choices []string, ok bool) {
+	active, ok = readValue(s.acpiDir(), "platform_profile")
+	if !ok || active == "" {
+		return "", nil, false
+	}
+	if v, ok := readValue(s.acpiDir(), "platform_profile_choices"); ok {
+		choices = strings.Fields(v)
+	}
+	return active, choices, true
+}
+
+// SetPlatformProfile writes profile to platform_profile after checking it
+// against the advertised choices
+func (s *Source) SetPlatformProfile(profile string) error {
+	_, choices, ok := s.PlatformProfile()
+	if !ok {
+		return fmt.Errorf("platform_profile not supported")
+	}
+	if len(choices) > 0 && !slices.Contains(choices, profile) {
+		return fmt.Errorf("unsupported platform profile %q (available: %s)", profile, strings.Join(choices, ", "))
+	}
+	return writeAttr(s.acpiDir(), "platform_profile", profile)
+}
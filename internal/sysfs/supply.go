@@ -0,0 +1,109 @@
+package sysfs
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Supply is an external power source such as an ACPI adapter (Mains) or a
+// USB-C port (USB, USB_PD, ...).
+type Supply struct {
+	Name      string
+	Type      string // power_supply type: Mains, USB, USB_PD, ...
+	USBType   string // Active usb_type (e.g. PD, PD_PPS, C), empty if not USB
+	Online    bool
+	MaxPowerW float64 // Negotiated voltage_max × current_max, NaN if unknown
+}
+
+// Kind describes the supply type, e.g. "USB/PD" or "Mains"
+func (s Supply) Kind() string {
+	if s.USBType != "" {
+		return s.Type + "/" + s.USBType
+	}
+	return s.Type
+}
+
+// Supplies returns every non-battery power supply in name order
+func (s *Source) Supplies() []Supply {
+	entries, err := os.ReadDir(s.powerSupplyDir())
+	if err != nil {
+		return nil
+	}
+	var out []Supply
+	for _, e := range entries {
+		dir := filepath.Join(s.powerSupplyDir(), e.Name())
+		t, ok := readValue(dir, "type")
+		if !ok || t == "Battery" {
+			continue
+		}
+		sup := Supply{Name: e.Name(), Type: t, MaxPowerW: math.NaN()}
+		if v, ok := readValue(dir, "online"); ok {
+			sup.Online = v == "1"
+		}
+		if v, ok := readValue(dir, "usb_type"); ok {
+			sup.USBType, _ = parseChoices(v)
+		}
+		volt, okV := readInt(dir, "voltage_max")
+		curr, okC := readInt(dir, "current_max")
+		if okV && okC && volt > 0 && curr > 0 {
+			sup.MaxPowerW = math.Round(float64(volt)*float64(curr)/1e12*10) / 10
+		}
+		out = append(out, sup)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SupplyState is the raw online and status attributes of a power supply,
+// as uevents carry them; empty where the supply has none
+type SupplyState struct {
+	Online string
+	Status string
+}
+
+// SupplyStates returns the state of every power supply, batteries
+// included, by name
+func (s *Source) SupplyStates() map[string]SupplyState {
+	entries, err := os.ReadDir(s.powerSupplyDir())
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]SupplyState, len(entries))
+	for _, e := range entries {
+		dir := filepath.Join(s.powerSupplyDir(), e.Name())
+		var st SupplyState
+		st.Online, _ = readValue(dir, "online")
+		st.Status, _ = readValue(dir, "status")
+		out[e.Name()] = st
+	}
+	return out
+}
+
+// ActiveCharger picks the online supply that is most likely feeding the
+// laptop. Many machines report both a generic ACPI adapter and the USB-C
+// port it is plugged into, so the supply with the highest negotiated power
+// wins, then any specific (non-Mains) supply, then the adapter.
+func (s *Source) ActiveCharger() (Supply, bool) {
+	var best Supply
+	found := false
+	rank := func(sup Supply) float64 {
+		if !math.IsNaN(sup.MaxPowerW) {
+			return 1000 + sup.MaxPowerW
+		}
+		if sup.Type != "Mains" {
+			return 1
+		}
+		return 0
+	}
+	for _, sup := range s.Supplies() {
+		if !sup.Online {
+			continue
+		}
+		if !found || rank(sup) > rank(best) {
+			best, found = sup, true
+		}
+	}
+	return best, found
+}
@@ -1,12 +1,35 @@
 package sysfs
 
 import (
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// DefaultRoot is the sysfs mount point used when no override is configured
+const DefaultRoot = "/sys"
+
+// Source reads power_supply attributes from a sysfs tree. Root defaults to
+// /sys and can point at a fake tree for testing or replaying another machine.
+type Source struct {
+	Root string
+}
+
+// NewSource returns a Source for root, or for DefaultRoot if root is empty
+func NewSource(root string) *Source {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Source{Root: root}
+}
+
+func (s *Source) powerSupplyDir() string {
+	return filepath.Join(s.Root, "class", "power_supply")
+}
+
 func readFirst(glob string) (string, bool) {
 	matches, _ := filepath.Glob(glob)
 	for _, p := range matches {
@@ -17,41 +40,291 @@ func readFirst(glob string) (string, bool) {
 	return "", false
 }
 
-func BatteryPercent() (int, bool) {
-	if s, ok := readFirst("/sys/class/power_supply/BAT*/capacity"); ok {
-		if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
-			return v, true
+func readValue(dir, name string) (string, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
+
+func readInt(dir, name string) (int64, bool) {
+	s, ok := readValue(dir, name)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// Pack is a single system battery (a type=Battery power supply that is not
+// a peripheral device). Charge-based batteries (charge_* in µAh) are
+// converted to energy using the pack voltage, so every pack reports Wh and W.
+type Pack struct {
+	Name             string
+	Percent          int
+	EnergyNow        float64 // Wh, NaN if unknown
+	EnergyFull       float64 // Wh, NaN if unknown
+	EnergyFullDesign float64 // Wh, NaN if unknown
+	PowerNow         float64 // W, NaN if unknown
+	Status           string  // Charging, Discharging, Not charging, Full, Unknown
+	CapacityLevel    string  // Critical, Low, Normal, High, Full, Unknown
+	CycleCount       int
+	HasCycleCount    bool
+}
+
+// Reading is a snapshot of every system battery plus the combined values
+// that are logged with each sample.
+type Reading struct {
+	AC               bool
+	Packs            []Pack
+	Percent          int
+	EnergyNow        float64 // Wh, NaN if unknown
+	EnergyFull       float64 // Wh, NaN if unknown
+	EnergyFullDesign float64 // Wh, NaN if unknown
+	PowerNow         float64 // W, NaN if unknown
+	Status           string
+	CapacityLevel    string
+	ChargeLimit      int    // Firmware charge end threshold, -1 if none
+	Charger          Supply // Active external supply; zero Name when on battery
+	ScreenOn         int    // 1 if a display is lit, 0 if not, -1 if unknown
+}
+
+// batteryDirs returns the power_supply directories of every system battery,
+// sorted by name so BAT0 always comes before BAT1.
+func (s *Source) batteryDirs() []string {
+	entries, err := os.ReadDir(s.powerSupplyDir())
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, e := range entries {
+		dir := filepath.Join(s.powerSupplyDir(), e.Name())
+		if t, _ := readValue(dir, "type"); t != "Battery" {
+			continue
 		}
+		// HID and Bluetooth peripherals also show up as batteries
+		if scope, _ := readValue(dir, "scope"); scope == "Device" {
+			continue
+		}
+		dirs = append(dirs, dir)
 	}
-	return 0, false
+	sort.Strings(dirs)
+	return dirs
 }
 
-// Returns true if AC online; falls back to BAT status
-func ACOnline() bool {
-	if s, ok := readFirst("/sys/class/power_supply/AC*/online"); ok {
-		return s == "1"
+// readEnergy reads energy_<suffix> in Wh, falling back to charge_<suffix>
+// multiplied by the given voltage (µV).
+func readEnergy(dir, suffix string, microVolts int64) float64 {
+	if v, ok := readInt(dir, "energy_"+suffix); ok {
+		return float64(v) / 1e6
+	}
+	if v, ok := readInt(dir, "charge_"+suffix); ok && microVolts > 0 {
+		return float64(v) * float64(microVolts) / 1e12
+	}
+	return math.NaN()
+}
+
+func readPack(dir string) (Pack, bool) {
+	p := Pack{
+		Name:          filepath.Base(dir),
+		PowerNow:      math.NaN(),
+		Status:        "Unknown",
+		CapacityLevel: "Unknown",
+	}
+
+	voltNow, _ := readInt(dir, "voltage_now")
+	voltDesign, ok := readInt(dir, "voltage_min_design")
+	if !ok {
+		voltDesign = voltNow
+	}
+	p.EnergyNow = readEnergy(dir, "now", voltNow)
+	p.EnergyFull = readEnergy(dir, "full", voltDesign)
+	p.EnergyFullDesign = readEnergy(dir, "full_design", voltDesign)
+
+	if v, ok := readInt(dir, "power_now"); ok {
+		p.PowerNow = math.Abs(float64(v)) / 1e6
+	} else if v, ok := readInt(dir, "current_now"); ok && voltNow > 0 {
+		// Some drivers report discharge current as negative
+		p.PowerNow = math.Abs(float64(v)) * float64(voltNow) / 1e12
+	}
+
+	if v, ok := readInt(dir, "capacity"); ok {
+		p.Percent = int(v)
+	} else {
+		// Some firmware omits capacity; derive it from the raw counters
+		if math.IsNaN(p.EnergyNow) || !(p.EnergyFull > 0) {
+			return Pack{}, false
+		}
+		p.Percent = int(math.Round(p.EnergyNow / p.EnergyFull * 100))
+	}
+
+	if s, ok := readValue(dir, "status"); ok && s != "" {
+		p.Status = s
+	}
+	if s, ok := readValue(dir, "capacity_level"); ok && s != "" {
+		p.CapacityLevel = s
+	}
+	if v, ok := readInt(dir, "cycle_count"); ok {
+		p.CycleCount, p.HasCycleCount = int(v), true
+	}
+	return p, true
+}
+
+// Packs returns a reading for every system battery in name order.
+func (s *Source) Packs() []Pack {
+	var packs []Pack
+	for _, dir := range s.batteryDirs() {
+		if p, ok := readPack(dir); ok {
+			packs = append(packs, p)
+		}
+	}
+	return packs
+}
+
+// Read takes a full snapshot of AC state and all packs. It returns false
+// when no battery could be read.
+func (s *Source) Read() (Reading, bool) {
+	packs := s.Packs()
+	pct, ok := CombinedPercent(packs)
+	if !ok {
+		return Reading{}, false
+	}
+	r := Reading{
+		AC:               s.ACOnline(),
+		Packs:            packs,
+		Percent:          pct,
+		EnergyNow:        sumField(packs, func(p Pack) float64 { return p.EnergyNow }),
+		EnergyFull:       sumField(packs, func(p Pack) float64 { return p.EnergyFull }),
+		EnergyFullDesign: sumField(packs, func(p Pack) float64 { return p.EnergyFullDesign }),
+		PowerNow:         sumField(packs, func(p Pack) float64 { return p.PowerNow }),
+		Status:           combinedStatus(packs),
+		CapacityLevel:    combinedCapacityLevel(packs),
+		ChargeLimit:      -1,
+		Charger:          Supply{MaxPowerW: math.NaN()},
+		ScreenOn:         -1,
+	}
+	if end, ok := s.ChargeEndThreshold(); ok {
+		r.ChargeLimit = end
+	}
+	if c, ok := s.ActiveCharger(); ok {
+		r.Charger = c
+	}
+	if on, ok := s.ScreenOn(); ok {
+		r.ScreenOn = 0
+		if on {
+			r.ScreenOn = 1
+		}
 	}
-	if s, ok := readFirst("/sys/class/power_supply/ACAD*/online"); ok {
-		return s == "1"
+	return r, true
+}
+
+// sumField adds a per-pack value, returning NaN if any pack lacks it.
+func sumField(packs []Pack, field func(Pack) float64) float64 {
+	var sum float64
+	for _, p := range packs {
+		sum += field(p)
+	}
+	return sum
+}
+
+// combinedStatus reports the most significant pack status: any charging
+// pack means the system is charging, then discharging, then the rest.
+func combinedStatus(packs []Pack) string {
+	for _, want := range []string{"Charging", "Discharging", "Not charging"} {
+		for _, p := range packs {
+			if p.Status == want {
+				return want
+			}
+		}
+	}
+	for _, p := range packs {
+		if p.Status != "Full" {
+			return p.Status
+		}
 	}
-	if s, ok := readFirst("/sys/class/power_supply/ADP*/online"); ok {
-		return s == "1"
+	return "Full"
+}
+
+// capacityLevels orders capacity_level values from worst to best.
+var capacityLevels = []string{"Critical", "Low", "Normal", "High", "Full"}
+
+// combinedCapacityLevel returns the worst known capacity_level of all packs.
+func combinedCapacityLevel(packs []Pack) string {
+	for _, level := range capacityLevels {
+		for _, p := range packs {
+			if p.CapacityLevel == level {
+				return level
+			}
+		}
+	}
+	return "Unknown"
+}
+
+// CombinedPercent returns the charge of all packs as one percentage,
+// weighted by each pack's full energy. Packs are weighted equally when any
+// capacity is unknown.
+func CombinedPercent(packs []Pack) (int, bool) {
+	if len(packs) == 0 {
+		return 0, false
+	}
+	if len(packs) == 1 {
+		return packs[0].Percent, true
+	}
+
+	weighted := true
+	for _, p := range packs {
+		if !(p.EnergyFull > 0) {
+			weighted = false
+			break
+		}
+	}
+
+	var sum, total float64
+	for _, p := range packs {
+		w := 1.0
+		if weighted {
+			w = p.EnergyFull
+		}
+		sum += w * float64(p.Percent)
+		total += w
+	}
+	return int(math.Round(sum / total)), true
+}
+
+func (s *Source) BatteryPercent() (int, bool) {
+	return CombinedPercent(s.Packs())
+}
+
+// Returns true if AC online; falls back to BAT status
+func (s *Source) ACOnline() bool {
+	for _, prefix := range []string{"AC", "ACAD", "ADP"} {
+		if v, ok := readFirst(filepath.Join(s.powerSupplyDir(), prefix+"*", "online")); ok {
+			return v == "1"
+		}
 	}
-	// Fallback: infer from status
-	if s, ok := readFirst("/sys/class/power_supply/BAT*/status"); ok {
-		switch s {
-		case "Charging", "Full":
-			return true
+	// Fallback: infer from status of any pack
+	for _, dir := range s.batteryDirs() {
+		if v, ok := readValue(dir, "status"); ok {
+			switch v {
+			case "Charging", "Full":
+				return true
+			}
 		}
 	}
 	return false
 }
 
-func BatteryCycleCount() (int, bool) {
-	if s, ok := readFirst("/sys/class/power_supply/BAT*/cycle_count"); ok {
-		if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
-			return v, true
+// BatteryCycleCount returns the highest cycle count across all packs.
+func (s *Source) BatteryCycleCount() (int, bool) {
+	count, found := 0, false
+	for _, p := range s.Packs() {
+		if p.HasCycleCount && (!found || p.CycleCount > count) {
+			count, found = p.CycleCount, true
 		}
 	}
-	return 0, false
+	return count, found
 }
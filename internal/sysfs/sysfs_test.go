@@ -0,0 +1,396 @@
+package sysfs
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixture builds a fake sysfs tree under a temp dir. Keys are paths
+// relative to class/power_supply, values are file contents.
+func fixture(t *testing.T, files map[string]string) *Source {
+	t.Helper()
+	rooted := make(map[string]string, len(files))
+	for rel, content := range files {
+		rooted[filepath.Join("class", "power_supply", rel)] = content
+	}
+	return rootFixture(t, rooted)
+}
+
+// rootFixture builds a fake sysfs tree from paths relative to the root
+func rootFixture(t *testing.T, files map[string]string) *Source {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewSource(root)
+}
+
+func TestNewSourceDefaultRoot(t *testing.T) {
+	if got := NewSource("").Root; got != DefaultRoot {
+		t.Errorf("Root = %q, want %q", got, DefaultRoot)
+	}
+}
+
+func TestBatteryPercentSinglePack(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":     "Battery",
+		"BAT0/capacity": "73",
+	})
+	pct, ok := src.BatteryPercent()
+	if !ok || pct != 73 {
+		t.Errorf("BatteryPercent() = %d, %t; want 73, true", pct, ok)
+	}
+}
+
+func TestBatteryPercentNoBattery(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"AC/type":   "Mains",
+		"AC/online": "1",
+	})
+	if _, ok := src.BatteryPercent(); ok {
+		t.Error("BatteryPercent() ok on a tree without batteries")
+	}
+	if _, ok := NewSource(t.TempDir()).BatteryPercent(); ok {
+		t.Error("BatteryPercent() ok on an empty tree")
+	}
+}
+
+func TestBatteryPercentWeightedByEnergy(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":        "Battery",
+		"BAT0/capacity":    "100",
+		"BAT0/energy_full": "20000000", // 20 Wh
+		"BAT1/type":        "Battery",
+		"BAT1/capacity":    "50",
+		"BAT1/energy_full": "60000000", // 60 Wh
+	})
+	packs := src.Packs()
+	if len(packs) != 2 || packs[0].Name != "BAT0" || packs[1].Name != "BAT1" {
+		t.Fatalf("Packs() = %+v, want BAT0 and BAT1", packs)
+	}
+	// (20*100 + 60*50) / 80 = 62.5
+	if pct, _ := CombinedPercent(packs); pct != 63 {
+		t.Errorf("CombinedPercent() = %d, want 63", pct)
+	}
+}
+
+func TestBatteryPercentEqualWeightsWithoutCapacity(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":     "Battery",
+		"BAT0/capacity": "80",
+		"BAT1/type":     "Battery",
+		"BAT1/capacity": "40",
+	})
+	if pct, _ := src.BatteryPercent(); pct != 60 {
+		t.Errorf("BatteryPercent() = %d, want 60", pct)
+	}
+}
+
+func TestPacksSkipsPeripherals(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":                        "Battery",
+		"BAT0/capacity":                    "90",
+		"hidpp_battery_0/type":             "Battery",
+		"hidpp_battery_0/scope":            "Device",
+		"hidpp_battery_0/capacity":         "10",
+		"ucsi-source-psy-USBC000:001/type": "USB",
+	})
+	packs := src.Packs()
+	if len(packs) != 1 || packs[0].Name != "BAT0" {
+		t.Errorf("Packs() = %+v, want only BAT0", packs)
+	}
+}
+
+func TestPackDerivesCapacityFromEnergy(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":        "Battery",
+		"BAT0/energy_now":  "30000000",
+		"BAT0/energy_full": "40000000",
+	})
+	packs := src.Packs()
+	if len(packs) != 1 || packs[0].Percent != 75 {
+		t.Errorf("Packs() = %+v, want 75%%", packs)
+	}
+}
+
+func TestPackMissingFiles(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type": "Battery",
+	})
+	if packs := src.Packs(); len(packs) != 0 {
+		t.Errorf("Packs() = %+v, want none for a battery without capacity", packs)
+	}
+}
+
+func TestReadChargeBasedBattery(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT1/type":               "Battery",
+		"BAT1/capacity":           "50",
+		"BAT1/status":             "Discharging",
+		"BAT1/capacity_level":     "Normal",
+		"BAT1/charge_now":         "2000000", // 2 Ah
+		"BAT1/charge_full":        "4000000",
+		"BAT1/charge_full_design": "5000000",
+		"BAT1/voltage_now":        "12000000", // 12 V
+		"BAT1/voltage_min_design": "11000000",
+		"BAT1/current_now":        "-1000000", // 1 A, negative while discharging
+		"AC/type":                 "Mains",
+		"AC/online":               "0",
+	})
+	r, ok := src.Read()
+	if !ok {
+		t.Fatal("Read() failed")
+	}
+	check := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	check("EnergyNow", r.EnergyNow, 24)
+	check("EnergyFull", r.EnergyFull, 44)
+	check("EnergyFullDesign", r.EnergyFullDesign, 55)
+	check("PowerNow", r.PowerNow, 12)
+	if r.AC || r.Status != "Discharging" || r.CapacityLevel != "Normal" {
+		t.Errorf("Read() = %+v", r)
+	}
+}
+
+func TestReadMissingTelemetryIsNaN(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":     "Battery",
+		"BAT0/capacity": "42",
+	})
+	r, ok := src.Read()
+	if !ok {
+		t.Fatal("Read() failed")
+	}
+	if !math.IsNaN(r.EnergyNow) || !math.IsNaN(r.PowerNow) {
+		t.Errorf("EnergyNow = %v, PowerNow = %v; want NaN", r.EnergyNow, r.PowerNow)
+	}
+	if r.Status != "Unknown" || r.CapacityLevel != "Unknown" {
+		t.Errorf("Status = %q, CapacityLevel = %q; want Unknown", r.Status, r.CapacityLevel)
+	}
+}
+
+func TestACOnline(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{"AC online", map[string]string{"AC/online": "1"}, true},
+		{"AC offline", map[string]string{"AC/online": "0"}, false},
+		{"ACAD online", map[string]string{"ACAD/online": "1"}, true},
+		{"ADP1 online", map[string]string{"ADP1/online": "1"}, true},
+		{"ADP1 offline beats charging status", map[string]string{
+			"ADP1/online": "0",
+			"BAT0/type":   "Battery",
+			"BAT0/status": "Charging",
+		}, false},
+		{"status fallback charging", map[string]string{
+			"BAT0/type":   "Battery",
+			"BAT0/status": "Charging",
+		}, true},
+		{"status fallback full", map[string]string{
+			"BAT0/type":   "Battery",
+			"BAT0/status": "Full",
+		}, true},
+		{"status fallback discharging", map[string]string{
+			"BAT0/type":   "Battery",
+			"BAT0/status": "Discharging",
+		}, false},
+		{"status fallback second pack", map[string]string{
+			"BAT0/type":   "Battery",
+			"BAT0/status": "Discharging",
+			"BAT1/type":   "Battery",
+			"BAT1/status": "Charging",
+		}, true},
+		{"nothing", map[string]string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fixture(t, tt.files).ACOnline(); got != tt.want {
+				t.Errorf("ACOnline() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatteryCycleCount(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":        "Battery",
+		"BAT0/capacity":    "50",
+		"BAT0/cycle_count": "120",
+		"BAT1/type":        "Battery",
+		"BAT1/capacity":    "50",
+		"BAT1/cycle_count": "340",
+	})
+	if n, ok := src.BatteryCycleCount(); !ok || n != 340 {
+		t.Errorf("BatteryCycleCount() = %d, %t; want 340, true", n, ok)
+	}
+
+	src = fixture(t, map[string]string{
+		"BAT0/type":     "Battery",
+		"BAT0/capacity": "50",
+	})
+	if _, ok := src.BatteryCycleCount(); ok {
+		t.Error("BatteryCycleCount() ok without cycle_count")
+	}
+}
+
+func TestActiveCharger(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"AC/type":                                 "Mains",
+		"AC/online":                               "1",
+		"ucsi-source-psy-USBC000:001/type":        "USB",
+		"ucsi-source-psy-USBC000:001/online":      "1",
+		"ucsi-source-psy-USBC000:001/usb_type":    "C [PD] PD_PPS",
+		"ucsi-source-psy-USBC000:001/voltage_max": "20000000",
+		"ucsi-source-psy-USBC000:001/current_max": "3250000",
+		"ucsi-source-psy-USBC000:002/type":        "USB",
+		"ucsi-source-psy-USBC000:002/online":      "0",
+		"BAT0/type":                               "Battery",
+	})
+	if n := len(src.Supplies()); n != 3 {
+		t.Fatalf("Supplies() returned %d supplies, want 3", n)
+	}
+	sup, ok := src.ActiveCharger()
+	if !ok {
+		t.Fatal("ActiveCharger() found nothing")
+	}
+	if sup.Name != "ucsi-source-psy-USBC000:001" || sup.Kind() != "USB/PD" || sup.MaxPowerW != 65 {
+		t.Errorf("ActiveCharger() = %+v", sup)
+	}
+}
+
+func TestSupplyStates(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"AC/type":      "Mains",
+		"AC/online":    "1",
+		"BAT0/type":    "Battery",
+		"BAT0/status":  "Not charging",
+		"BAT0/present": "1",
+	})
+	got := src.SupplyStates()
+	if len(got) != 2 || got["AC"] != (SupplyState{Online: "1"}) || got["BAT0"] != (SupplyState{Status: "Not charging"}) {
+		t.Errorf("SupplyStates() = %+v", got)
+	}
+}
+
+func TestPeripherals(t *testing.T) {
+	src := fixture(t, map[string]string{
+		"BAT0/type":                                "Battery",
+		"BAT0/capacity":                            "70",
+		"hid-aa:bb:cc:dd:ee:ff-battery/type":       "Battery",
+		"hid-aa:bb:cc:dd:ee:ff-battery/scope":      "Device",
+		"hid-aa:bb:cc:dd:ee:ff-battery/model_name": "MX Master 3",
+		"hid-aa:bb:cc:dd:ee:ff-battery/capacity":   "35",
+		"hidpp_battery_0/type":                     "Battery",
+		"hidpp_battery_0/scope":                    "Device",
+		"hidpp_battery_0/capacity_level":           "Low",
+		"hid-11:22:33:44:55:66-battery/type":       "Battery",
+		"hid-11:22:33:44:55:66-battery/scope":      "Device",
+		"hid-11:22:33:44:55:66-battery/model_name": "Disconnected Keyboard",
+	})
+	got := src.Peripherals()
+	if len(got) != 2 {
+		t.Fatalf("Peripherals() = %+v", got)
+	}
+	if p := got[0]; p.Model != "MX Master 3" || p.Percent != 35 {
+		t.Errorf("mouse = %+v", p)
+	}
+	if p := got[1]; p.Name != "hidpp_battery_0" || p.Percent != 20 {
+		t.Errorf("level-only device = %+v", p)
+	}
+	if n := len(src.Packs()); n != 1 {
+		t.Errorf("Packs() returned %d packs, want 1", n)
+	}
+}
+
+func TestCPUFreqMHz(t *testing.T) {
+	src := rootFixture(t, map[string]string{
+		"devices/system/cpu/cpu0/cpufreq/scaling_cur_freq":    "1200000",
+		"devices/system/cpu/cpu1/cpufreq/scaling_cur_freq":    "2800000",
+		"devices/system/cpu/cpufreq/policy0/scaling_cur_freq": "9999999",
+	})
+	if got, ok := src.CPUFreqMHz(); !ok || got != 2000 {
+		t.Errorf("CPUFreqMHz() = %v, %t; want 2000, true", got, ok)
+	}
+	if _, ok := rootFixture(t, nil).CPUFreqMHz(); ok {
+		t.Error("CPUFreqMHz() succeeded without cpufreq")
+	}
+}
+
+func TestPlatformProfile(t *testing.T) {
+	src := rootFixture(t, map[string]string{
+		"firmware/acpi/platform_profile":         "balanced",
+		"firmware/acpi/platform_profile_choices": "low-power balanced performance",
+	})
+	active, choices, ok := src.PlatformProfile()
+	if !ok || active != "balanced" || len(choices) != 3 {
+		t.Fatalf("PlatformProfile() = %q, %v, %v", active, choices, ok)
+	}
+	if err := src.SetPlatformProfile("turbo"); err == nil {
+		t.Error("SetPlatformProfile accepted an unsupported profile")
+	}
+	if err := src.SetPlatformProfile("low-power"); err != nil {
+		t.Fatal(err)
+	}
+	if active, _, _ := src.PlatformProfile(); active != "low-power" {
+		t.Errorf("profile after set = %q", active)
+	}
+	if _, _, ok := rootFixture(t, nil).PlatformProfile(); ok {
+		t.Error("PlatformProfile() ok without firmware/acpi")
+	}
+}
+
+func TestWakeupCounts(t *testing.T) {
+	src := rootFixture(t, map[string]string{
+		"class/wakeup/wakeup0/name":        "rtc0",
+		"class/wakeup/wakeup0/event_count": "4",
+		"class/wakeup/wakeup1/name":        "XHC",
+		"class/wakeup/wakeup1/event_count": "10",
+		"class/wakeup/wakeup2/name":        "XHC",
+		"class/wakeup/wakeup2/event_count": "1",
+		"class/wakeup/wakeup3/event_count": "7",
+	})
+	got := src.WakeupCounts()
+	if len(got) != 3 || got["rtc0"] != 4 || got["XHC"] != 11 || got["wakeup3"] != 7 {
+		t.Errorf("WakeupCounts() = %v", got)
+	}
+	if got := rootFixture(t, nil).WakeupCounts(); got != nil {
+		t.Errorf("WakeupCounts() without class/wakeup = %v", got)
+	}
+}
+
+func TestFirmwareVersions(t *testing.T) {
+	src := rootFixture(t, map[string]string{
+		"class/dmi/id/bios_version":            "N32ET91W (1.67 )",
+		"class/power_supply/BAT0/type":         "Battery",
+		"class/power_supply/BAT0/manufacturer": "SMP",
+		"class/power_supply/BAT0/model_name":   "5B10W51867",
+		"class/power_supply/BAT1/type":         "Battery",
+		"class/power_supply/ACAD/type":         "Mains",
+		"class/power_supply/ACAD/model_name":   "adapter",
+	})
+	if v, ok := src.BIOSVersion(); !ok || v != "N32ET91W (1.67 )" {
+		t.Errorf("BIOSVersion() = %q, %t", v, ok)
+	}
+	models := src.BatteryModels()
+	if len(models) != 1 || models[0].Pack != "BAT0" || models[0].String() != "SMP 5B10W51867" {
+		t.Errorf("BatteryModels() = %+v", models)
+	}
+	if _, ok := rootFixture(t, nil).BIOSVersion(); ok {
+		t.Error("BIOSVersion() ok without class/dmi")
+	}
+}
@@ -0,0 +1,23 @@
+package sysfs
+
+import "path/filepath"
+
+// WakeupCounts returns the event_count of every wakeup source under
+// class/wakeup, summed by source name (e.g. rtc0, PNP0C0D:00, XHC)
+func (s *Source) WakeupCounts() map[string]int64 {
+	dirs, _ := filepath.Glob(filepath.Join(s.Root, "class", "wakeup", "wakeup*"))
+	if len(dirs) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64)
+	for _, dir := range dirs {
+		name, ok := readValue(dir, "name")
+		if !ok || name == "" {
+			name = filepath.Base(dir)
+		}
+		if n, ok := readInt(dir, "event_count"); ok {
+			counts[name] += n
+		}
+	}
+	return counts
+}
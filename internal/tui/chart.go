@@ -2,9 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/peripheral"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/widgets"
 
 	"github.com/mum4k/termdash/cell"
@@ -17,23 +19,24 @@ func ProcessChartData(rows []analytics.Row) ([]widgets.TimeSeries, error) {
 		return nil, fmt.Errorf("no data available")
 	}
 
-	var series []widgets.TimeSeries
-	var chargingPoints []widgets.TimePoint
-	var dischargingPoints []widgets.TimePoint
-
+	var chargingPoints, heldPoints, dischargingPoints []widgets.TimePoint
 	for _, row := range rows {
 		point := widgets.TimePoint{
 			Time:  row.T,
 			Value: row.Batt,
 			State: row.AC,
 		}
-		if row.AC {
+		switch chartState(row) {
+		case analytics.StateCharging:
 			chargingPoints = append(chargingPoints, point)
-		} else {
+		case analytics.StateDischarging:
 			dischargingPoints = append(dischargingPoints, point)
+		default:
+			heldPoints = append(heldPoints, point)
 		}
 	}
 
+	var series []widgets.TimeSeries
 	if len(chargingPoints) > 0 {
 		series = append(series, widgets.TimeSeries{
 			Name:   "Charging",
@@ -41,6 +44,13 @@ func ProcessChartData(rows []analytics.Row) ([]widgets.TimeSeries, error) {
 			Color:  cell.ColorNumber(46), // Bright green for better contrast
 		})
 	}
+	if len(heldPoints) > 0 {
+		series = append(series, widgets.TimeSeries{
+			Name:   "Full/held",
+			Points: heldPoints,
+			Color:  cell.ColorNumber(51), // Cyan
+		})
+	}
 	if len(dischargingPoints) > 0 {
 		series = append(series, widgets.TimeSeries{
 			Name:   "Discharging",
@@ -51,6 +61,74 @@ func ProcessChartData(rows []analytics.Row) ([]widgets.TimeSeries, error) {
 	return series, nil
 }
 
+// chartState collapses a row into charging, discharging or held
+// (StateNotCharging). Rows without a known state fall back on the AC flag;
+// a plugged-in "Unknown" from the kernel usually means the firmware is
+// holding the pack.
+func chartState(row analytics.Row) analytics.State {
+	switch {
+	case row.State == analytics.StateCharging, row.State == analytics.StateDischarging:
+		return row.State
+	case row.State.Held():
+		return analytics.StateNotCharging
+	case !row.AC:
+		return analytics.StateDischarging
+	case row.Status != "" || row.Batt >= 100:
+		return analytics.StateNotCharging
+	}
+	return analytics.StateCharging
+}
+
+// ProcessLoadData converts logged CPU utilisation into an overlay series on
+// the same 0-100 scale as the battery level
+func ProcessLoadData(rows []analytics.Row) []widgets.TimeSeries {
+	var points []widgets.TimePoint
+	for _, row := range rows {
+		if math.IsNaN(row.CPUUtil) {
+			continue
+		}
+		points = append(points, widgets.TimePoint{Time: row.T, Value: row.CPUUtil})
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return []widgets.TimeSeries{{
+		Name:   "CPU load",
+		Points: points,
+		Color:  cell.ColorNumber(208), // Orange
+	}}
+}
+
+// peripheralColors tell device series apart from the main battery lines
+var peripheralColors = []cell.Color{
+	cell.ColorNumber(39),  // Blue
+	cell.ColorNumber(213), // Pink
+	cell.ColorNumber(220), // Yellow
+	cell.ColorNumber(180), // Tan
+	cell.ColorNumber(141), // Purple
+}
+
+func peripheralColor(i int) cell.Color {
+	return peripheralColors[i%len(peripheralColors)]
+}
+
+// ProcessPeripheralData converts per-device readings into one series each
+func ProcessPeripheralData(devices [][]peripheral.Reading) []widgets.TimeSeries {
+	series := make([]widgets.TimeSeries, 0, len(devices))
+	for i, dev := range devices {
+		points := make([]widgets.TimePoint, len(dev))
+		for j, r := range dev {
+			points[j] = widgets.TimePoint{Time: r.T, Value: r.Percent}
+		}
+		series = append(series, widgets.TimeSeries{
+			Name:   dev[len(dev)-1].Label(),
+			Points: points,
+			Color:  peripheralColor(i),
+		})
+	}
+	return series
+}
+
 // UpdateChartWidget updates the chart widget with new data
 func UpdateChartWidget(chartWidget *widgets.BatteryChart, series []widgets.TimeSeries) error {
 	chartWidget.ClearSeries()
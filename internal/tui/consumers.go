@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/consumers"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// consumerWindow is the look-back of the top consumers panel
+const consumerWindow = 2 * time.Hour
+
+// BuildConsumerLines lists the processes that used the most battery energy
+// over the last consumerWindow
+func BuildConsumerLines(cfg config.Config, rows []analytics.Row) []LineSpec {
+	path := config.ProcessPath(cfg)
+	list, total, err := consumers.Report(path, rows, config.Now(cfg).Add(-consumerWindow))
+	if err != nil {
+		return []LineSpec{{Text: fmt.Sprintf("Could not read %s: %v", path, err), Color: cell.ColorRed, UseColor: true}}
+	}
+	if total == 0 {
+		return []LineSpec{{Text: fmt.Sprintf("No drain on battery in the last %.0fh", consumerWindow.Hours())}}
+	}
+
+	lines := []LineSpec{{Text: fmt.Sprintf("%.2f Wh drained, by CPU share:", total)}}
+	for i, c := range list {
+		if i >= 8 || c.EnergyWh == 0 {
+			break
+		}
+		color, useColor := cell.Color(0), false
+		if c.Share >= 0.25 {
+			color, useColor = cell.ColorYellow, true
+		}
+		lines = append(lines, LineSpec{Text: fmt.Sprintf("--    %-15s %5.2f Wh %3.0f%%", c.Name, c.EnergyWh, c.Share*100), Color: color, UseColor: useColor})
+	}
+	return lines
+}
+
+// UpdateConsumerText writes the top consumers list to the text widget
+func UpdateConsumerText(textWidget *text.Text, cfg config.Config, rows []analytics.Row) {
+	textWidget.Reset()
+	for _, ln := range BuildConsumerLines(cfg, rows) {
+		if ln.UseColor {
+			textWidget.Write(ln.Text+"\n", text.WriteCellOpts(cell.FgColor(ln.Color)))
+		} else {
+			textWidget.Write(ln.Text + "\n")
+		}
+	}
+}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -36,6 +37,13 @@ func BuildStatusLines(info StatusInfo) []LineSpec {
 		acStatus = "Plugged In"
 		acIcon = ""
 	}
+	if info.Latest.AC && info.Latest.Charger != "" {
+		acStatus += fmt.Sprintf(" via %s (%s", info.Latest.Charger, info.Latest.ChargerType)
+		if !math.IsNaN(info.Latest.ChargerMaxW) {
+			acStatus += fmt.Sprintf(", %.0f W", info.Latest.ChargerMaxW)
+		}
+		acStatus += ")"
+	}
 	appendLine(fmt.Sprintf("%s  AC Status: %s", acIcon, acStatus), cell.ColorYellow, true)
 
 	// Delta since last transition
@@ -59,7 +67,9 @@ func BuildStatusLines(info StatusInfo) []LineSpec {
 	}
 	if info.Latest.AC {
 		// If we have an estimate duration, also show the ETA (by: time)
-		if info.EstimateDuration > 0 {
+		if info.HeldAtLimit {
+			appendLine(fmt.Sprintf("--    Held at limit: %.0f%% (charge target %d%%)", info.Latest.Batt, info.MaxChargePercent), cell.ColorGreen, true)
+		} else if info.EstimateDuration > 0 {
 			appendLine(fmt.Sprintf("--    Time to Full (%d%%): %s (by: %s)", info.MaxChargePercent, info.Estimate, info.EstimateETA.Format("15:04")), 0, false)
 		} else {
 			appendLine(fmt.Sprintf("--    Time to Full (%d%%): %s", info.MaxChargePercent, info.Estimate), 0, false)
@@ -79,16 +89,55 @@ func BuildStatusLines(info StatusInfo) []LineSpec {
 	appendLine("󰤁  Battery Status:", 0, false)
 	// Current battery & cycles
 	appendLine(fmt.Sprintf("--    Current Battery: %.1f%%", info.Latest.Batt), 0, false)
-	if info.HasCycleCount {
-		appendLine(fmt.Sprintf("--    Battery Cycles: %d", info.CycleCount), 0, false)
+	for _, p := range info.Latest.Packs {
+		appendLine(fmt.Sprintf("--        %s: %.1f%%", p.Name, p.Batt), 0, false)
+	}
+	var cycles []string
+	for _, p := range info.Packs {
+		if !p.HasCycleCount {
+			continue
+		}
+		if len(info.Packs) > 1 {
+			cycles = append(cycles, fmt.Sprintf("%s %d", p.Name, p.CycleCount))
+		} else {
+			cycles = append(cycles, fmt.Sprintf("%d", p.CycleCount))
+		}
+	}
+	if len(cycles) > 0 {
+		appendLine(fmt.Sprintf("--    Battery Cycles: %s", strings.Join(cycles, ", ")), 0, false)
+	}
+	if !math.IsNaN(info.Latest.PowerW) {
+		appendLine(fmt.Sprintf("--    Power Draw: %.1f W (%s)", info.Latest.PowerW, info.Latest.Status), 0, false)
+	}
+	if !math.IsNaN(info.Latest.EnergyNow) && !math.IsNaN(info.Latest.EnergyFull) {
+		appendLine(fmt.Sprintf("--    Energy: %.1f / %.1f Wh", info.Latest.EnergyNow, info.Latest.EnergyFull), 0, false)
 	}
 
 	// Rate + estimate
-	appendLine(fmt.Sprintf("--    %s: %s %s", info.RateLabel, info.SlopeStr, info.Confidence), 0, false)
+	rateLabel := info.RateLabel
+	if info.MobileOnly {
+		rateLabel += " (mobile only)"
+	}
+	appendLine(fmt.Sprintf("--    %s: %s %s", rateLabel, info.SlopeStr, info.Confidence), 0, false)
 
 	// Spacer
 	appendLine("", 0, false)
 
+	// Peripheral batteries, colored like their chart series
+	if len(info.Peripherals) > 0 {
+		appendLine("󰍽  Peripherals:", 0, false)
+		for i, dev := range info.Peripherals {
+			last := dev[len(dev)-1]
+			line := fmt.Sprintf("--    %s: %.0f%%", last.Label(), last.Percent)
+			// Disconnected devices keep their last level; say how old it is
+			if age := info.Latest.T.Sub(last.T); age > time.Hour {
+				line += fmt.Sprintf(" (seen %s ago)", FormatDurationAuto(age.Round(time.Minute)))
+			}
+			appendLine(line, peripheralColor(i), true)
+		}
+		appendLine("", 0, false)
+	}
+
 	// Screen-on time section
 	appendLine("󱎴  Screen-On Time:", 0, false)
 
@@ -109,10 +158,24 @@ func BuildStatusLines(info StatusInfo) []LineSpec {
 	if info.TodayScreenOnTime.TotalActiveTime > 0 {
 		appendLine(fmt.Sprintf("--    Today's total: %s", FormatDurationAuto(info.TodayScreenOnTime.TotalActiveTime)), 0, false)
 	}
+	if info.TodayScreenOnTime.IdleTime > 0 {
+		appendLine(fmt.Sprintf("--        Interactive: %s, idle: %s",
+			FormatDurationAuto(info.TodayScreenOnTime.InteractiveTime),
+			FormatDurationAuto(info.TodayScreenOnTime.IdleTime)), 0, false)
+	}
+	if info.TodayScreenOnTime.DockedTime > 0 {
+		appendLine(fmt.Sprintf("--        Mobile: %s, docked: %s",
+			FormatDurationAuto(info.TodayScreenOnTime.MobileTime),
+			FormatDurationAuto(info.TodayScreenOnTime.DockedTime)), 0, false)
+	}
+	if info.TodayScreenOnTime.ScreenOffTime > 0 {
+		appendLine(fmt.Sprintf("--    Awake, screen off: %s", FormatDurationAuto(info.TodayScreenOnTime.ScreenOffTime)), 0, false)
+	}
 
 	// Last suspend/shutdown event details
 	if info.LastSuspendEvent != nil {
-		appendLine(fmt.Sprintf("--    Last suspend: %s - %s (lasted %s)",
+		appendLine(fmt.Sprintf("--    Last %s: %s - %s (lasted %s)",
+			info.LastSuspendEvent.Kind,
 			info.LastSuspendEvent.StartTime.Format("Jan 2 15:04"),
 			info.LastSuspendEvent.EndTime.Format("Jan 2 15:04"),
 			FormatDurationAuto(info.LastSuspendEvent.Duration)), 0, false)
@@ -130,11 +193,23 @@ func BuildStatusLines(info StatusInfo) []LineSpec {
 			info.LastSuspendEvent.BatteryBefore,
 			info.LastSuspendEvent.BatteryAfter,
 			changeStr), 0, false)
+		if !math.IsNaN(info.LastSuspendRatio) {
+			appendLine(fmt.Sprintf("--        %.1f× the usual overnight drain (battery-zen suspends)", info.LastSuspendRatio), cell.ColorYellow, true)
+		}
 	}
 
 	// Spacer
 	appendLine("", 0, false)
 
+	// Boot sessions with the battery used in each
+	if len(info.BootSessions) > 0 {
+		appendLine("  Boot Sessions:", 0, false)
+		for i := len(info.BootSessions) - 1; i >= 0; i-- {
+			appendLine("--    "+FormatBootSession(info.BootSessions[i], i == len(info.BootSessions)-1), 0, false)
+		}
+		appendLine("", 0, false)
+	}
+
 	// Summary section
 	appendLine("  Data Summary:", 0, false)
 	appendLine(fmt.Sprintf("--    Total samples: %d (spanning %s)", info.TotalSamples, FormatDurationAuto(info.TimeRange.Round(time.Minute))), 0, false)
@@ -166,27 +241,32 @@ func UpdateStatusText(textWidget *text.Text, info StatusInfo) {
 
 // DailySOTData represents screen-on time data for a single day
 type DailySOTData struct {
-	Date     time.Time
-	SOTHours float64
-	IsToday  bool
-	HasData  bool
+	Date      time.Time
+	SOTHours  float64
+	IdleHours float64 // Part of SOTHours without input
+	IsToday   bool
+	HasData   bool
 }
 
-// CalculateWeeklySOTData calculates daily SOT for the past 7 days
+// SOTDays is how many days the SOT bar chart covers
+const SOTDays = 7
+
+// CalculateWeeklySOTData calculates daily SOT for the past SOTDays days
 func CalculateWeeklySOTData(rows []analytics.Row, gapThresholdMinutes int) []DailySOTData {
 	now := time.Now()
 	var weekData []DailySOTData
 
-	// Calculate for the past 7 days (including today)
-	for i := 6; i >= 0; i-- {
+	// Calculate for the past SOTDays days (including today)
+	for i := SOTDays - 1; i >= 0; i-- {
 		date := now.AddDate(0, 0, -i)
 		sotResult := analytics.CalculateDailyScreenOnTime(rows, date, gapThresholdMinutes)
 
 		weekData = append(weekData, DailySOTData{
-			Date:     date,
-			SOTHours: sotResult.TotalActiveTime.Hours(),
-			IsToday:  i == 0,
-			HasData:  sotResult.TotalActiveTime > 0,
+			Date:      date,
+			SOTHours:  sotResult.TotalActiveTime.Hours(),
+			IdleHours: sotResult.IdleTime.Hours(),
+			IsToday:   i == 0,
+			HasData:   sotResult.TotalActiveTime > 0,
 		})
 	}
 
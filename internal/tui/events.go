@@ -8,6 +8,7 @@ import (
 
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/peripheral"
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/widgets"
 
 	"github.com/mum4k/termdash/cell"
@@ -17,7 +18,7 @@ import (
 )
 
 // SetupDataRefresh sets up periodic data refresh and returns the update function
-func SetupDataRefresh(ctx context.Context, logPath string, uiParams *UIParams, chartWidget *widgets.BatteryChart, textWidget *text.Text, sotBarChart *widgets.SOTBarChart, cfg config.Config, c *container.Container, alpha float64, readCSVFunc func(string) ([]analytics.Row, error)) (func() error, error) {
+func SetupDataRefresh(ctx context.Context, logPath string, uiParams *UIParams, chartWidget *widgets.BatteryChart, textWidget *text.Text, sotBarChart *widgets.SOTBarChart, healthWidget, consumerWidget *text.Text, cfg config.Config, c *container.Container, alpha float64, readCSVFunc func(string) ([]analytics.Row, error)) (func() error, error) {
 	updateData := func() error {
 		rows, err := readCSVFunc(logPath)
 		if err != nil || len(rows) == 0 {
@@ -33,11 +34,28 @@ func SetupDataRefresh(ctx context.Context, logPath string, uiParams *UIParams, c
 		}
 
 		// Process chart data
-		series, err := ProcessChartData(rows)
+		chartRows := rows
+		if uiParams.ShowMobileOnly() {
+			chartRows = analytics.MobileRows(rows)
+		}
+		series, err := ProcessChartData(chartRows)
 		if err != nil {
 			return fmt.Errorf("processing chart data: %v", err)
 		}
 
+		if uiParams.ShowLoad() {
+			series = append(series, ProcessLoadData(chartRows)...)
+		}
+
+		// Peripheral batteries are drawn over the main battery series
+		ps := &peripheral.Store{Path: config.PeripheralPath(cfg)}
+		readings, err := ps.Load()
+		if err != nil {
+			log.Printf("peripherals: %v", err)
+		}
+		devices := peripheral.Series(readings)
+		series = append(series, ProcessPeripheralData(devices)...)
+
 		// Update chart
 		if err := UpdateChartWidget(chartWidget, series); err != nil {
 			return fmt.Errorf("updating chart: %v", err)
@@ -49,6 +67,7 @@ func SetupDataRefresh(ctx context.Context, logPath string, uiParams *UIParams, c
 
 		// Generate and update status text
 		statusInfo := GenerateStatusInfo(rows, alpha, uiParams, logPath, cfg)
+		statusInfo.Peripherals = devices
 		UpdateStatusText(textWidget, statusInfo)
 
 		// Update SOT bar chart
@@ -56,6 +75,12 @@ func SetupDataRefresh(ctx context.Context, logPath string, uiParams *UIParams, c
 			return fmt.Errorf("updating SOT bar chart: %v", err)
 		}
 
+		// Update battery health panel
+		UpdateHealthText(healthWidget, cfg)
+
+		// Update top consumers panel
+		UpdateConsumerText(consumerWidget, cfg, rows)
+
 		return nil
 	}
 
@@ -81,7 +106,7 @@ func SetupDataRefresh(ctx context.Context, logPath string, uiParams *UIParams, c
 }
 
 // CreateKeyboardHandler creates the keyboard event handler for the TUI
-func CreateKeyboardHandler(cancel context.CancelFunc, updateData func() error) func(*terminalapi.Keyboard) {
+func CreateKeyboardHandler(cancel context.CancelFunc, uiParams *UIParams, updateData func() error) func(*terminalapi.Keyboard) {
 	return func(k *terminalapi.Keyboard) {
 		if k.Key == 'q' || k.Key == 'Q' {
 			cancel()
@@ -91,5 +116,17 @@ func CreateKeyboardHandler(cancel context.CancelFunc, updateData func() error) f
 				log.Printf("Manual refresh error: %v", err)
 			}
 		}
+		if k.Key == 'l' || k.Key == 'L' {
+			uiParams.ToggleLoad()
+			if err := updateData(); err != nil {
+				log.Printf("Manual refresh error: %v", err)
+			}
+		}
+		if k.Key == 'm' || k.Key == 'M' {
+			uiParams.ToggleMobileOnly()
+			if err := updateData(); err != nil {
+				log.Printf("Manual refresh error: %v", err)
+			}
+		}
 	}
 }
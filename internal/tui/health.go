@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/config"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/health"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// BuildHealthLines loads the health history and formats one entry per pack
+func BuildHealthLines(cfg config.Config) []LineSpec {
+	hs := &health.Store{Path: config.HealthPath(cfg)}
+	snaps, err := hs.Load()
+	if err != nil {
+		return []LineSpec{{Text: fmt.Sprintf("Could not read %s: %v", hs.Path, err), Color: cell.ColorRed, UseColor: true}}
+	}
+
+	packs := health.Packs(snaps)
+	if len(packs) == 0 {
+		return []LineSpec{{Text: "No health data yet"}}
+	}
+
+	var lines []LineSpec
+	for _, name := range packs {
+		tr := health.FitTrend(snaps, name, float64(cfg.HealthTarget))
+
+		// Highlight packs that are at or near the replacement threshold
+		color, useColor := cell.Color(0), false
+		if !math.IsNaN(tr.HealthPercent) && tr.HealthPercent <= tr.TargetPercent {
+			color, useColor = cell.ColorRed, true
+		} else if !math.IsNaN(tr.HealthPercent) && tr.HealthPercent <= tr.TargetPercent+5 {
+			color, useColor = cell.ColorYellow, true
+		}
+		lines = append(lines, LineSpec{Text: tr.Summary(), Color: color, UseColor: useColor})
+		lines = append(lines, LineSpec{Text: "--    " + tr.Projection()})
+	}
+	return lines
+}
+
+// UpdateHealthText writes the battery health summary to the text widget
+func UpdateHealthText(textWidget *text.Text, cfg config.Config) {
+	textWidget.Reset()
+	for _, ln := range BuildHealthLines(cfg) {
+		if ln.UseColor {
+			textWidget.Write(ln.Text+"\n", text.WriteCellOpts(cell.FgColor(ln.Color)))
+		} else {
+			textWidget.Write(ln.Text + "\n")
+		}
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"time"
 
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/peripheral"
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
 )
 
 // UIParams holds the real-time adjustable parameters
 type UIParams struct {
-	Refresh time.Duration
-	mu      sync.RWMutex
+	Refresh    time.Duration
+	HideLoad   bool // CPU load overlay on the chart, toggled with l
+	MobileOnly bool // Chart and predictions skip docked samples, toggled with m
+	mu         sync.RWMutex
 }
 
 // Get returns thread-safe copies of the parameters
@@ -20,6 +24,34 @@ func (p *UIParams) Get() time.Duration {
 	return p.Refresh
 }
 
+// ShowLoad reports whether the CPU load overlay is visible
+func (p *UIParams) ShowLoad() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return !p.HideLoad
+}
+
+// ToggleLoad shows or hides the CPU load overlay
+func (p *UIParams) ToggleLoad() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.HideLoad = !p.HideLoad
+}
+
+// ShowMobileOnly reports whether docked samples are filtered out
+func (p *UIParams) ShowMobileOnly() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.MobileOnly
+}
+
+// ToggleMobileOnly switches between all samples and mobile-only samples
+func (p *UIParams) ToggleMobileOnly() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.MobileOnly = !p.MobileOnly
+}
+
 // StatusInfo holds information needed for status display
 type StatusInfo struct {
 	Latest            analytics.Row
@@ -39,10 +71,14 @@ type StatusInfo struct {
 	EndTime           string
 	ConfigStr         string
 	LogPath           string
-	MaxChargePercent  int
-	CycleCount        int
-	HasCycleCount     bool
+	MaxChargePercent  int  // Effective charge target (config or firmware limit)
+	HeldAtLimit       bool // Plugged in but firmware is not charging
+	MobileOnly        bool // Predictions use only undocked samples
+	Packs             []sysfs.Pack
 	ScreenOnTime      analytics.ScreenOnTimeResult
 	TodayScreenOnTime analytics.ScreenOnTimeResult
 	LastSuspendEvent  *analytics.SuspendEvent
+	LastSuspendRatio  float64                 // Drain over the median night when abnormal, else NaN
+	BootSessions      []analytics.BootSession // Most recent last, at most maxBootSessions
+	Peripherals       [][]peripheral.Reading  // Per-device history, in chart series order
 }
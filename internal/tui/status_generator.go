@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
@@ -9,6 +10,9 @@ import (
 	"github.com/Prajwal-Prathiksh/battery-zen/internal/sysfs"
 )
 
+// maxBootSessions is how many recent boots the status panel lists
+const maxBootSessions = 3
+
 // FindLastACTransition finds when the current AC status started
 func FindLastACTransition(rows []analytics.Row) (time.Time, float64) {
 	if len(rows) == 0 {
@@ -39,6 +43,10 @@ func GenerateStatusInfo(rows []analytics.Row, alpha float64, uiParams *UIParams,
 	// For regression, consider only the most recent contiguous samples with the same AC state
 	currentACState := latest.AC
 	contiguousSamples := analytics.FilterContiguousACState(rows, currentACState)
+	mobileOnly := uiParams.ShowMobileOnly()
+	if mobileOnly {
+		contiguousSamples = analytics.TrailingMobile(contiguousSamples)
+	}
 
 	var est string
 	var slopeStr string
@@ -47,8 +55,12 @@ func GenerateStatusInfo(rows []analytics.Row, alpha float64, uiParams *UIParams,
 	var estimateDuration time.Duration
 	var estimateETA time.Time
 
+	// Aim for the firmware charge threshold when one is active
+	chargeTarget := analytics.ChargeTarget(rows, cfg.MaxChargePercent)
+	heldAtLimit := analytics.IsHeldAtLimit(latest, chargeTarget)
+
 	if len(contiguousSamples) >= 2 {
-		rate, estimateMins, conf, ok := analytics.CalculateRateAndEstimate(contiguousSamples, latest.Batt, alpha, cfg.MaxChargePercent)
+		rate, estimateMins, conf, ok := analytics.CalculateRateAndEstimate(contiguousSamples, latest.Batt, alpha, chargeTarget)
 		confidence = conf
 		if ok {
 			if currentACState {
@@ -82,6 +94,9 @@ func GenerateStatusInfo(rows []analytics.Row, alpha float64, uiParams *UIParams,
 		if !currentACState {
 			acStateStr = "discharging"
 		}
+		if mobileOnly {
+			acStateStr = "mobile " + acStateStr
+		}
 		confidence = fmt.Sprintf("(need ≥2 %s samples)", acStateStr)
 	}
 
@@ -113,8 +128,8 @@ func GenerateStatusInfo(rows []analytics.Row, alpha float64, uiParams *UIParams,
 		configStr = fmt.Sprintf("  Config files: %s (+ %d more)", existingConfigPaths[len(existingConfigPaths)-1], len(existingConfigPaths)-1) // nf-md-cog
 	}
 
-	// Get battery cycle count
-	cycleCount, hasCycleCount := sysfs.BatteryCycleCount()
+	// Get live per-battery readings (cycle counts)
+	packs := sysfs.NewSource(cfg.SysfsRoot).Packs()
 
 	// Calculate screen-on time and suspend events
 	screenOnTime := analytics.CalculateScreenOnTime(rows, cfg.SuspendGapMinutes)
@@ -123,10 +138,21 @@ func GenerateStatusInfo(rows []analytics.Row, alpha float64, uiParams *UIParams,
 	now := time.Now()
 	todayScreenOnTime := analytics.CalculateDailyScreenOnTime(rows, now, cfg.SuspendGapMinutes)
 
-	// Get the most recent suspend event
-	var lastSuspendEvent *analytics.SuspendEvent
-	if len(screenOnTime.SuspendEvents) > 0 {
-		lastSuspendEvent = &screenOnTime.SuspendEvents[len(screenOnTime.SuspendEvents)-1]
+	// Get the most recent suspend, hibernate or shutdown
+	lastSuspendEvent := analytics.LastSleep(screenOnTime.SuspendEvents)
+	lastSuspendRatio := math.NaN()
+	if lastSuspendEvent != nil {
+		drains, _ := analytics.SuspendDrains(screenOnTime.SuspendEvents, cfg.AbnormalDrainFactor)
+		for _, d := range drains {
+			if d.Abnormal && d.EndTime.Equal(lastSuspendEvent.EndTime) {
+				lastSuspendRatio = d.Ratio
+			}
+		}
+	}
+
+	bootSessions := analytics.BootSessions(rows, cfg.SuspendGapMinutes)
+	if len(bootSessions) > maxBootSessions {
+		bootSessions = bootSessions[len(bootSessions)-maxBootSessions:]
 	}
 
 	return StatusInfo{
@@ -147,11 +173,14 @@ func GenerateStatusInfo(rows []analytics.Row, alpha float64, uiParams *UIParams,
 		EndTime:           endTime,
 		ConfigStr:         configStr,
 		LogPath:           logPath,
-		MaxChargePercent:  cfg.MaxChargePercent,
-		CycleCount:        cycleCount,
-		HasCycleCount:     hasCycleCount,
+		MaxChargePercent:  chargeTarget,
+		HeldAtLimit:       heldAtLimit,
+		MobileOnly:        mobileOnly,
+		Packs:             packs,
 		ScreenOnTime:      screenOnTime,
 		TodayScreenOnTime: todayScreenOnTime,
 		LastSuspendEvent:  lastSuspendEvent,
+		LastSuspendRatio:  lastSuspendRatio,
+		BootSessions:      bootSessions,
 	}
 }
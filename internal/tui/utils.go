@@ -2,7 +2,10 @@ package tui
 
 import (
 	"fmt"
+	"math"
 	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/analytics"
 )
 
 // FormatDurationAuto formats a duration as HH:mm if <24h, or as Xd Yh if >=24h
@@ -17,3 +20,24 @@ func FormatDurationAuto(dur time.Duration) string {
 		return fmt.Sprintf("%dd %dh", days, hours)
 	}
 }
+
+// FormatBootSession describes one boot: when it ran, how long it was awake
+// and asleep, and the battery it used. current marks the running boot.
+func FormatBootSession(b analytics.BootSession, current bool) string {
+	end := b.End.Format("Jan 2 15:04")
+	if current {
+		end = "now"
+	}
+	s := fmt.Sprintf("%s → %s: up %s", b.Boot.Format("Jan 2 15:04"), end, FormatDurationAuto(b.Awake()))
+	if b.Asleep > 0 {
+		s += fmt.Sprintf(", asleep %s", FormatDurationAuto(b.Asleep))
+	}
+	s += fmt.Sprintf(", used %.0f%%", b.Used)
+	if !math.IsNaN(b.UsedWh) {
+		s += fmt.Sprintf(" (%.1f Wh)", b.UsedWh)
+	}
+	if b.Charged > 0 {
+		s += fmt.Sprintf(", charged %.0f%%", b.Charged)
+	}
+	return s
+}
@@ -47,11 +47,11 @@ func CreateSOTBarChart() *widgets.SOTBarChart {
 }
 
 // CreateUILayout creates the TUI container layout with all widgets
-func CreateUILayout(t terminalapi.Terminal, chartWidget *widgets.BatteryChart, textWidget *text.Text, sotBarChart *widgets.SOTBarChart) (*container.Container, error) {
+func CreateUILayout(t terminalapi.Terminal, chartWidget *widgets.BatteryChart, textWidget *text.Text, sotBarChart *widgets.SOTBarChart, healthWidget, consumerWidget *text.Text) (*container.Container, error) {
 	return container.New(
 		t,
 		container.Border(linestyle.Light),
-		container.BorderTitle("Battery Zen TUI - Tab/Shift+Tab: focus, q: quit, r: refresh"),
+		container.BorderTitle("Battery Zen TUI - Tab/Shift+Tab: focus, q: quit, r: refresh, l: CPU load, m: mobile only"),
 		container.KeyFocusNext(keyboard.KeyTab),
 		container.KeyFocusPrevious(keyboard.KeyBacktab),
 		container.SplitHorizontal(
@@ -69,9 +69,28 @@ func CreateUILayout(t terminalapi.Terminal, chartWidget *widgets.BatteryChart, t
 						container.PlaceWidget(textWidget),
 					),
 					container.Right(
-						container.Border(linestyle.Light),
-						container.BorderTitle("Daily Screen-On Time (7 days)"),
-						container.PlaceWidget(sotBarChart),
+						container.SplitHorizontal(
+							container.Top(
+								container.Border(linestyle.Light),
+								container.BorderTitle("Daily Screen-On Time (7 days, gray = idle)"),
+								container.PlaceWidget(sotBarChart),
+							),
+							container.Bottom(
+								container.SplitVertical(
+									container.Left(
+										container.Border(linestyle.Light),
+										container.BorderTitle("Battery Health"),
+										container.PlaceWidget(healthWidget),
+									),
+									container.Right(
+										container.Border(linestyle.Light),
+										container.BorderTitle("Top Consumers (2h)"),
+										container.PlaceWidget(consumerWidget),
+									),
+								),
+							),
+							container.SplitPercent(65),
+						),
 					),
 					container.SplitPercent(65), // Status text takes 65%, bar chart takes 35%
 				),
@@ -0,0 +1,162 @@
+// Package uevent listens for kernel power_supply uevents so the daemon can
+// sample as soon as AC or charge status changes instead of waiting for the
+// next poll.
+package uevent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Event is a single kernel uevent
+type Event struct {
+	Action    string
+	DevPath   string
+	Subsystem string
+	Env       map[string]string
+}
+
+// IsPowerSupply reports whether the event came from the power_supply class
+func (e Event) IsPowerSupply() bool {
+	return e.Subsystem == "power_supply"
+}
+
+// Parse decodes a kernel uevent datagram of the form
+// "action@devpath\0KEY=VALUE\0KEY=VALUE\0...".
+func Parse(msg []byte) (Event, error) {
+	fields := bytes.Split(bytes.TrimRight(msg, "\x00"), []byte{0})
+	if len(fields) == 0 || len(fields[0]) == 0 {
+		return Event{}, errors.New("empty uevent")
+	}
+
+	ev := Event{Env: make(map[string]string)}
+	header := string(fields[0])
+	action, devpath, ok := strings.Cut(header, "@")
+	if !ok {
+		// udev-originated messages start with "libudev" and are not ours
+		return Event{}, fmt.Errorf("not a kernel uevent: %q", header)
+	}
+	ev.Action, ev.DevPath = action, devpath
+
+	for _, f := range fields[1:] {
+		k, v, ok := strings.Cut(string(f), "=")
+		if !ok {
+			continue
+		}
+		ev.Env[k] = v
+	}
+	if v := ev.Env["ACTION"]; v != "" {
+		ev.Action = v
+	}
+	if v := ev.Env["DEVPATH"]; v != "" {
+		ev.DevPath = v
+	}
+	ev.Subsystem = ev.Env["SUBSYSTEM"]
+	return ev, nil
+}
+
+// Source delivers uevents one at a time. Receive blocks until an event
+// arrives or the source is closed.
+type Source interface {
+	Receive() (Event, error)
+	Close() error
+}
+
+// packetSource reads one datagram per Read call from any packet-oriented
+// connection: a netlink socket in production, a socketpair end in tests.
+type packetSource struct {
+	conn io.ReadCloser
+	buf  []byte
+}
+
+// NewSource wraps a datagram connection as a Source. Each Read on conn must
+// return exactly one uevent message.
+func NewSource(conn io.ReadCloser) Source {
+	return &packetSource{conn: conn, buf: make([]byte, 64*1024)}
+}
+
+func (s *packetSource) Receive() (Event, error) {
+	for {
+		n, err := s.conn.Read(s.buf)
+		if err != nil {
+			return Event{}, err
+		}
+		ev, err := Parse(s.buf[:n])
+		if err != nil {
+			continue // skip malformed or foreign messages
+		}
+		return ev, nil
+	}
+}
+
+func (s *packetSource) Close() error {
+	return s.conn.Close()
+}
+
+// Listen opens a NETLINK_KOBJECT_UEVENT socket subscribed to kernel events
+func Listen() (Source, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	// Group 1 carries kernel-originated events (udev rebroadcasts use group 2)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+	// Non-blocking lets the runtime poller wake Receive when Close is called
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return NewSource(os.NewFile(uintptr(fd), "uevent")), nil
+}
+
+// Tracker remembers the last online/status value of each power supply and
+// reports events that change either. Periodic capacity updates, which the
+// kernel also emits as "change" events, are ignored.
+type Tracker struct {
+	last map[string]string
+}
+
+// Seed records the state a supply is in before any event arrives, from its
+// sysfs online and status attributes, so the first event for it reports
+// only a real change
+func (t *Tracker) Seed(name, online, status string) {
+	if online == "" && status == "" {
+		return
+	}
+	if t.last == nil {
+		t.last = make(map[string]string)
+	}
+	t.last[name] = online + "|" + status
+}
+
+// Changed reports whether ev is a power_supply event that flips AC online
+// state or battery status compared to what was last seen.
+func (t *Tracker) Changed(ev Event) bool {
+	if !ev.IsPowerSupply() {
+		return false
+	}
+	if t.last == nil {
+		t.last = make(map[string]string)
+	}
+	name := ev.Env["POWER_SUPPLY_NAME"]
+	if name == "" {
+		name = ev.DevPath
+	}
+
+	state := ev.Env["POWER_SUPPLY_ONLINE"] + "|" + ev.Env["POWER_SUPPLY_STATUS"]
+	if state == "|" {
+		// add/remove of a supply (e.g. a USB-C source) without state fields
+		return ev.Action == "add" || ev.Action == "remove"
+	}
+	prev, seen := t.last[name]
+	t.last[name] = state
+	return !seen || prev != state
+}
@@ -0,0 +1,162 @@
+package uevent
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// socketpair returns a Source reading one end of a datagram socketpair and
+// the other end for the test to write kernel-style messages into.
+func socketpair(t *testing.T) (Source, *os.File) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	for _, fd := range fds {
+		if err := syscall.SetNonblock(fd, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := NewSource(os.NewFile(uintptr(fds[0]), "uevent-test"))
+	peer := os.NewFile(uintptr(fds[1]), "uevent-peer")
+	t.Cleanup(func() {
+		src.Close()
+		peer.Close()
+	})
+	return src, peer
+}
+
+func message(header string, env ...string) []byte {
+	return []byte(header + "\x00" + strings.Join(env, "\x00") + "\x00")
+}
+
+func TestParse(t *testing.T) {
+	ev, err := Parse(message("change@/devices/LNXSYSTM:00/ACPI0003:00/power_supply/AC",
+		"ACTION=change",
+		"DEVPATH=/devices/LNXSYSTM:00/ACPI0003:00/power_supply/AC",
+		"SUBSYSTEM=power_supply",
+		"POWER_SUPPLY_NAME=AC",
+		"POWER_SUPPLY_ONLINE=1",
+		"SEQNUM=4242",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Action != "change" || !ev.IsPowerSupply() || ev.Env["POWER_SUPPLY_ONLINE"] != "1" {
+		t.Errorf("Parse() = %+v", ev)
+	}
+	if !strings.HasSuffix(ev.DevPath, "/power_supply/AC") {
+		t.Errorf("DevPath = %q", ev.DevPath)
+	}
+}
+
+func TestParseRejectsUdevMessages(t *testing.T) {
+	if _, err := Parse([]byte("libudev\x00\xfe\xed\xca\xfe")); err == nil {
+		t.Error("Parse() accepted a libudev message")
+	}
+	if _, err := Parse(nil); err == nil {
+		t.Error("Parse() accepted an empty message")
+	}
+}
+
+func TestSourceReceive(t *testing.T) {
+	src, peer := socketpair(t)
+
+	// Garbage first: the source must skip it and deliver the next event
+	if _, err := peer.Write([]byte("libudev\x00junk")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := peer.Write(message("change@/devices/BAT0",
+		"SUBSYSTEM=power_supply", "POWER_SUPPLY_NAME=BAT0", "POWER_SUPPLY_STATUS=Charging")); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := src.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Env["POWER_SUPPLY_NAME"] != "BAT0" || ev.Env["POWER_SUPPLY_STATUS"] != "Charging" {
+		t.Errorf("Receive() = %+v", ev)
+	}
+}
+
+func TestSourceCloseUnblocksReceive(t *testing.T) {
+	src, _ := socketpair(t)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := src.Receive()
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	src.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Receive() after Close = %v, want os.ErrClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Receive() still blocked after Close")
+	}
+}
+
+func TestTrackerChanged(t *testing.T) {
+	ps := func(name string, env ...string) Event {
+		ev := Event{Action: "change", Subsystem: "power_supply", Env: map[string]string{"POWER_SUPPLY_NAME": name}}
+		for _, kv := range env {
+			k, v, _ := strings.Cut(kv, "=")
+			ev.Env[k] = v
+		}
+		return ev
+	}
+
+	var tr Tracker
+	steps := []struct {
+		name string
+		ev   Event
+		want bool
+	}{
+		{"first AC state", ps("AC", "POWER_SUPPLY_ONLINE=0"), true},
+		{"repeated AC state", ps("AC", "POWER_SUPPLY_ONLINE=0"), false},
+		{"plug in", ps("AC", "POWER_SUPPLY_ONLINE=1"), true},
+		{"first battery status", ps("BAT0", "POWER_SUPPLY_STATUS=Charging", "POWER_SUPPLY_CAPACITY=50"), true},
+		{"capacity tick", ps("BAT0", "POWER_SUPPLY_STATUS=Charging", "POWER_SUPPLY_CAPACITY=51"), false},
+		{"held at limit", ps("BAT0", "POWER_SUPPLY_STATUS=Not charging", "POWER_SUPPLY_CAPACITY=80"), true},
+		{"other subsystem", Event{Subsystem: "usb", Env: map[string]string{}}, false},
+		{"usb-c source added", Event{Action: "add", Subsystem: "power_supply", Env: map[string]string{"POWER_SUPPLY_NAME": "ucsi"}}, true},
+	}
+	for _, s := range steps {
+		if got := tr.Changed(s.ev); got != s.want {
+			t.Errorf("%s: Changed() = %t, want %t", s.name, got, s.want)
+		}
+	}
+}
+
+func TestTrackerSeeded(t *testing.T) {
+	ev := func(name, key, value string) Event {
+		return Event{Action: "change", Subsystem: "power_supply",
+			Env: map[string]string{"POWER_SUPPLY_NAME": name, key: value, "POWER_SUPPLY_CAPACITY": "70"}}
+	}
+	var tr Tracker
+	tr.Seed("AC", "1", "")
+	tr.Seed("BAT0", "", "Charging")
+	tr.Seed("hidpp_battery_0", "", "")
+	if tr.Changed(ev("AC", "POWER_SUPPLY_ONLINE", "1")) {
+		t.Error("first periodic AC event after seeding reported a change")
+	}
+	if tr.Changed(ev("BAT0", "POWER_SUPPLY_STATUS", "Charging")) {
+		t.Error("first capacity tick after seeding reported a change")
+	}
+	if !tr.Changed(ev("BAT0", "POWER_SUPPLY_STATUS", "Full")) {
+		t.Error("status change after seeding missed")
+	}
+	if !tr.Changed(ev("hidpp_battery_0", "POWER_SUPPLY_STATUS", "Discharging")) {
+		t.Error("supply without seeded state ignored")
+	}
+}
@@ -0,0 +1,119 @@
+// Package versions records the kernel, BIOS and battery packs in use and
+// when each changed, so drain can be compared across upgrades.
+package versions
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+)
+
+var header = []string{"timestamp", "component", "version"}
+
+// Components tracked besides the battery packs
+const (
+	Kernel = "kernel"
+	BIOS   = "bios"
+)
+
+// batteryPrefix namespaces pack components, e.g. battery:BAT0
+const batteryPrefix = "battery:"
+
+// Battery returns the component name of one pack's manufacturer and model
+func Battery(pack string) string {
+	return batteryPrefix + pack
+}
+
+// Change is one component moving to a new version. From is empty the first
+// time a component is seen.
+type Change struct {
+	T         time.Time
+	Component string
+	From      string
+	To        string
+}
+
+// Upgrade reports whether the change replaced a known version
+func (c Change) Upgrade() bool {
+	return c.From != ""
+}
+
+// Store is the version history file. Like the health history it is kept
+// apart from the sample log so it survives trimming.
+type Store struct {
+	Path string
+}
+
+func (s *Store) table() *logfile.Table {
+	return &logfile.Table{Path: s.Path, Header: header}
+}
+
+// Load reads the history as changes in file order. A missing file yields
+// none.
+func (s *Store) Load() ([]Change, error) {
+	recs, err := s.table().Load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Change
+	last := make(map[string]string)
+	for _, rec := range recs {
+		t, err := time.Parse(time.RFC3339, rec[0])
+		if err != nil {
+			continue
+		}
+		out = append(out, Change{T: t, Component: rec[1], From: last[rec[1]], To: rec[2]})
+		last[rec[1]] = rec[2]
+	}
+	return out, nil
+}
+
+// Current returns the latest version of every component in changes
+func Current(changes []Change) map[string]string {
+	cur := make(map[string]string)
+	for _, c := range changes {
+		cur[c.Component] = c.To
+	}
+	return cur
+}
+
+// Record appends the components in seen whose version differs from the
+// last recorded one and returns those changes. Empty versions are ignored,
+// so a component that cannot be read is not logged as removed.
+func (s *Store) Record(seen map[string]string, t time.Time) ([]Change, error) {
+	history, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	last := Current(history)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		v := strings.TrimSpace(seen[name])
+		if v == "" || v == last[name] {
+			continue
+		}
+		changes = append(changes, Change{T: t, Component: name, From: last[name], To: v})
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, s.append(changes)
+}
+
+// append writes changes, creating the file with a header if needed
+func (s *Store) append(changes []Change) error {
+	rows := make([][]string, len(changes))
+	for i, c := range changes {
+		rows[i] = []string{c.T.Format(time.RFC3339), c.Component, c.To}
+	}
+	return s.table().Append(rows...)
+}
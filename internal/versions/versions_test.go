@@ -0,0 +1,48 @@
+package versions
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "versions.csv")}
+	t0 := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	got, err := s.Record(map[string]string{Kernel: "6.7.4", BIOS: "N32ET91W (1.67 )", Battery("BAT0"): "SMP 5B10W51867"}, t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Upgrade() {
+		t.Fatalf("first Record() = %+v", got)
+	}
+
+	// Unchanged and unreadable components are not logged
+	if got, _ := s.Record(map[string]string{Kernel: "6.7.4", BIOS: ""}, t0.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("unchanged Record() = %+v", got)
+	}
+
+	got, err = s.Record(map[string]string{Kernel: "6.8.1", BIOS: "N32ET91W (1.67 )"}, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].From != "6.7.4" || got[0].To != "6.8.1" {
+		t.Errorf("upgrade Record() = %+v", got)
+	}
+
+	history, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("Load() returned %d changes", len(history))
+	}
+	last := history[3]
+	if last.Component != Kernel || last.From != "6.7.4" || !last.T.Equal(t0.Add(time.Hour)) {
+		t.Errorf("last change = %+v", last)
+	}
+	if cur := Current(history); cur[BIOS] != "N32ET91W (1.67 )" || cur[Kernel] != "6.8.1" {
+		t.Errorf("Current() = %v", cur)
+	}
+}
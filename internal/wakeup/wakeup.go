@@ -0,0 +1,162 @@
+// Package wakeup records which wakeup sources fired across each suspend,
+// from /sys/class/wakeup event counts the daemon takes as logind announces
+// the suspend and the resume.
+package wakeup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Prajwal-Prathiksh/battery-zen/internal/logfile"
+)
+
+var header = []string{"timestamp", "start", "slept_secs", "wakeups"}
+
+// minSleep ignores suspended-time deltas that are clock jitter
+const minSleep = time.Second
+
+// Count is how often one wakeup source fired
+type Count struct {
+	Name   string
+	Events int64
+}
+
+// Record is one suspend: when the system went to sleep and woke, and the
+// wakeup events in between, most frequent first
+type Record struct {
+	T       time.Time // Resume
+	Start   time.Time // Suspend
+	Slept   time.Duration
+	Wakeups []Count
+}
+
+// Delta returns the sources whose count grew from before to after, most
+// events first. Sources that appeared since count from zero.
+func Delta(before, after map[string]int64) []Count {
+	var out []Count
+	for name, n := range after {
+		if d := n - before[name]; d > 0 {
+			out = append(out, Count{Name: name, Events: d})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Events != out[j].Events {
+			return out[i].Events > out[j].Events
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Tracker diffs the wakeup counts taken just before a suspend against
+// those taken just after the resume, so events in awake time are left out
+type Tracker struct {
+	counts    map[string]int64
+	suspended time.Duration
+	at        time.Time
+}
+
+// Suspend records the counts and the time suspended since boot as the
+// system is about to sleep
+func (t *Tracker) Suspend(counts map[string]int64, suspended time.Duration, at time.Time) {
+	t.counts, t.suspended, t.at = counts, suspended, at
+}
+
+// Resume returns the suspend that just ended. Without a snapshot from
+// Suspend, on a machine without wakeup sources, or when the suspend was
+// aborted before the system slept, there is none.
+func (t *Tracker) Resume(counts map[string]int64, suspended time.Duration, at time.Time) (Record, bool) {
+	before, beforeSuspended, start := t.counts, t.suspended, t.at
+	t.counts = nil
+	if before == nil || counts == nil {
+		return Record{}, false
+	}
+	slept := suspended - beforeSuspended
+	if slept < minSleep {
+		return Record{}, false
+	}
+	return Record{T: at, Start: start, Slept: slept, Wakeups: Delta(before, counts)}, true
+}
+
+// sanitize keeps source names from breaking the name=count;... encoding
+var sanitize = strings.NewReplacer(";", "_", "=", "_", ",", "_", "\n", "_")
+
+func formatCounts(counts []Count) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s=%d", sanitize.Replace(c.Name), c.Events)
+	}
+	return strings.Join(parts, ";")
+}
+
+func parseCounts(s string) []Count {
+	var out []Count
+	for _, part := range strings.Split(s, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			out = append(out, Count{Name: name, Events: n})
+		}
+	}
+	return out
+}
+
+// Store is the per-suspend wakeup log
+type Store struct {
+	Path string
+}
+
+func (s *Store) table() *logfile.Table {
+	return &logfile.Table{Path: s.Path, Header: header}
+}
+
+// Load reads all records in file order. A missing file yields none.
+func (s *Store) Load() ([]Record, error) {
+	recs, err := s.table().Load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Record
+	for _, rec := range recs {
+		t, err1 := time.Parse(time.RFC3339, rec[0])
+		start, err2 := time.Parse(time.RFC3339, rec[1])
+		secs, err3 := strconv.ParseFloat(rec[2], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		out = append(out, Record{
+			T:       t,
+			Start:   start,
+			Slept:   time.Duration(secs * float64(time.Second)),
+			Wakeups: parseCounts(rec[3]),
+		})
+	}
+	return out, nil
+}
+
+// Append writes one record, creating the file with a header if needed
+func (s *Store) Append(r Record) error {
+	return s.table().Append([]string{
+		r.T.Format(time.RFC3339),
+		r.Start.Format(time.RFC3339),
+		strconv.FormatFloat(r.Slept.Seconds(), 'f', 0, 64),
+		formatCounts(r.Wakeups),
+	})
+}
+
+// Find returns the record of the suspend that lies within start and end,
+// the samples either side of it
+func Find(records []Record, start, end time.Time) (Record, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if !r.Start.Before(start) && !r.T.After(end) {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
@@ -0,0 +1,63 @@
+package wakeup
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	var tr Tracker
+	if _, ok := tr.Resume(map[string]int64{"rtc0": 1}, time.Hour, t0); ok {
+		t.Fatal("resume without a suspend snapshot reported a suspend")
+	}
+	tr.Suspend(map[string]int64{"rtc0": 1, "XHC": 5}, time.Hour, t0)
+	got, ok := tr.Resume(map[string]int64{"rtc0": 4, "XHC": 5, "PNP0C0D:00": 1}, 8*time.Hour, t0.Add(7*time.Hour))
+	if !ok {
+		t.Fatal("resume not detected")
+	}
+	want := []Count{{"rtc0", 3}, {"PNP0C0D:00", 1}}
+	if got.Slept != 7*time.Hour || got.Start != t0 || got.T != t0.Add(7*time.Hour) || !reflect.DeepEqual(got.Wakeups, want) {
+		t.Errorf("Resume() = %+v", got)
+	}
+	if _, ok := tr.Resume(map[string]int64{"rtc0": 5}, 9*time.Hour, t0.Add(9*time.Hour)); ok {
+		t.Error("second resume reused the suspend snapshot")
+	}
+	// Suspend aborted before the system slept
+	tr.Suspend(map[string]int64{"rtc0": 5}, 8*time.Hour, t0.Add(10*time.Hour))
+	if _, ok := tr.Resume(map[string]int64{"rtc0": 6}, 8*time.Hour, t0.Add(10*time.Hour)); ok {
+		t.Error("aborted suspend reported")
+	}
+	// No wakeup sources
+	tr.Suspend(nil, 8*time.Hour, t0.Add(11*time.Hour))
+	if _, ok := tr.Resume(nil, 9*time.Hour, t0.Add(12*time.Hour)); ok {
+		t.Error("suspend without wakeup sources reported")
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "suspends.csv")}
+	t0 := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	rec := Record{T: t0.Add(8 * time.Hour), Start: t0, Slept: 8 * time.Hour,
+		Wakeups: []Count{{"rtc0", 3}, {"a;b=c", 1}}}
+	if err := s.Append(rec); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Load()
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Load() = %+v, %v", got, err)
+	}
+	rec.Wakeups[1].Name = "a_b_c"
+	if !reflect.DeepEqual(got[0], rec) {
+		t.Errorf("Load() = %+v, want %+v", got[0], rec)
+	}
+	// The samples either side of the suspend are up to a minute off it
+	if _, ok := Find(got, t0.Add(-time.Minute), t0.Add(8*time.Hour+time.Minute)); !ok {
+		t.Error("Find() missed the record")
+	}
+	if _, ok := Find(got, t0.Add(9*time.Hour), t0.Add(10*time.Hour)); ok {
+		t.Error("Find() matched a later suspend")
+	}
+}
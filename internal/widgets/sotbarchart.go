@@ -17,10 +17,11 @@ import (
 
 // SOTBarData represents daily screen-on time data for a single day
 type SOTBarData struct {
-	Date        time.Time
-	SOTDuration time.Duration
-	IsToday     bool
-	HasData     bool
+	Date         time.Time
+	SOTDuration  time.Duration
+	IdleDuration time.Duration // Part of SOTDuration without input, drawn dimmed on top
+	IsToday      bool
+	HasData      bool
 }
 
 // SOTBarChart displays daily screen-on time as bars with HH:MM annotations
@@ -31,6 +32,7 @@ type SOTBarChart struct {
 	// Colors
 	barColor      cell.Color
 	todayBarColor cell.Color
+	idleBarColor  cell.Color
 	textColor     cell.Color
 	titleColor    cell.Color
 }
@@ -52,6 +54,7 @@ func CreateSOTBarChart(opts ...SOTBarChartOption) *SOTBarChart {
 		title:         "Daily Screen-On Time (7 days)",
 		barColor:      cell.ColorCyan,
 		todayBarColor: cell.ColorYellow,
+		idleBarColor:  cell.ColorNumber(240),
 		textColor:     cell.ColorWhite,
 		titleColor:    cell.ColorCyan,
 	}
@@ -90,10 +93,11 @@ func (bc *SOTBarChart) UpdateData(rows []analytics.Row, gapThresholdMinutes int)
 		sotResult := analytics.CalculateDailyScreenOnTime(rows, date, gapThresholdMinutes)
 
 		weekData = append(weekData, SOTBarData{
-			Date:        date,
-			SOTDuration: sotResult.TotalActiveTime,
-			IsToday:     i == 0,
-			HasData:     sotResult.TotalActiveTime > 0,
+			Date:         date,
+			SOTDuration:  sotResult.TotalActiveTime,
+			IdleDuration: sotResult.IdleTime,
+			IsToday:      i == 0,
+			HasData:      sotResult.TotalActiveTime > 0,
 		})
 	}
 
@@ -169,12 +173,22 @@ func (bc *SOTBarChart) drawBar(cvs *canvas.Canvas, barArea image.Rectangle, data
 		barColor = bc.todayBarColor
 	}
 
+	// Idle time sits dimmed on top of the interactive part
+	idleHeight := 0
+	if data.SOTDuration > 0 {
+		idleHeight = int(float64(barHeight)*data.IdleDuration.Seconds()/data.SOTDuration.Seconds() + 0.5)
+	}
+
 	// Draw the bar
 	barTop := barArea.Max.Y - barHeight
 	for y := barTop; y < barArea.Max.Y; y++ {
+		color := barColor
+		if y < barTop+idleHeight {
+			color = bc.idleBarColor
+		}
 		for x := barX; x < barEndX; x++ {
 			if x >= barArea.Min.X && x < barArea.Max.X {
-				cvs.SetCell(image.Point{x, y}, '█', cell.FgColor(barColor))
+				cvs.SetCell(image.Point{x, y}, '█', cell.FgColor(color))
 			}
 		}
 	}